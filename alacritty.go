@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// ansiColorNames are the names used by several terminals for the eight
+// base colors, in palette order.
+var ansiColorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+func detectAlacritty(content string) bool {
+	return strings.Contains(content, "[colors.primary]") || strings.Contains(content, "[colors.normal]") ||
+		(strings.Contains(content, "colors:") && strings.Contains(content, "primary:"))
+}
+
+// parseAlacritty reads an Alacritty theme, either in the current TOML
+// layout or in the legacy YAML one, accepting both "#rrggbb" and
+// "0xrrggbb" values.
+func parseAlacritty(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	var entries []kvEntry
+	if strings.Contains(joinLines(lines), "[colors") {
+		entries = parseINI(lines)
+	} else {
+		entries = parseYAML(lines)
+	}
+
+	fields := kvLookup(entries)
+
+	mapping := map[string]string{
+		"colors.primary.foreground": "foreground",
+		"colors.primary.background": "background",
+		"colors.cursor.cursor":      "cursorColor",
+		"colors.cursor.text":        "cursorColor2",
+	}
+
+	for i, name := range ansiColorNames {
+		mapping["colors.normal."+name] = fmt.Sprintf("color%d", i)
+		mapping["colors.bright."+name] = fmt.Sprintf("color%d", i+8)
+	}
+
+	values := map[string]resource{}
+
+	for path, key := range mapping {
+		e, found := fields[path]
+		if !found {
+			continue
+		}
+
+		res := e.resource()
+		res.value = alacrittyColor(res.value)
+
+		// the cursor can be set to follow the cell colors, which KiTTY
+		// can't do, so these fall back to the primary colors
+		if strings.HasPrefix(strings.ToLower(res.value), "cell") {
+			continue
+		}
+
+		debugf("%s: using %s: %s", e.source, path, e.value)
+		values[key] = res
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "Alacritty theme")
+	return values, nil
+}
+
+// alacrittyColor converts the "0xrrggbb" notation to "#rrggbb".
+func alacrittyColor(s string) string {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return "#" + s[2:]
+	}
+
+	return s
+}
+
+// alacrittySection is a table of an Alacritty theme, with its colors in
+// the order they are written.
+type alacrittySection struct {
+	name   string
+	keys   []string
+	colors map[string]color.RGBA
+}
+
+// alacrittySections returns the tables of an Alacritty theme for the
+// palette. The cursor text falls back to the background, and the
+// selection table is only there when the input sets one.
+func alacrittySections(p palette) []alacrittySection {
+	primary := alacrittySection{name: "primary", keys: []string{"background", "foreground"}, colors: map[string]color.RGBA{
+		"background": p.keys["background"],
+		"foreground": p.keys["foreground"],
+	}}
+
+	cursor := alacrittySection{name: "cursor", keys: []string{"text", "cursor"}, colors: map[string]color.RGBA{
+		"text":   p.keys["background"],
+		"cursor": p.keys["cursorColor"],
+	}}
+
+	if c, found := p.keys["cursorColor2"]; found {
+		cursor.colors["text"] = c
+	}
+
+	normal := alacrittySection{name: "normal", keys: ansiColorNames, colors: map[string]color.RGBA{}}
+	bright := alacrittySection{name: "bright", keys: ansiColorNames, colors: map[string]color.RGBA{}}
+	for i, name := range ansiColorNames {
+		normal.colors[name] = p.keys[fmt.Sprintf("color%d", i)]
+		bright.colors[name] = p.keys[fmt.Sprintf("color%d", i+8)]
+	}
+
+	sections := []alacrittySection{primary, cursor}
+
+	selection := alacrittySection{name: "selection", colors: map[string]color.RGBA{}}
+	for _, field := range [][2]string{{"text", "highlightTextColor"}, {"background", "highlightColor"}} {
+		if c, found := p.keys[field[1]]; found {
+			selection.keys = append(selection.keys, field[0])
+			selection.colors[field[0]] = c
+		}
+	}
+
+	if len(selection.keys) != 0 {
+		sections = append(sections, selection)
+	}
+
+	return append(sections, normal, bright)
+}
+
+// writeAlacritty writes the colors as an Alacritty theme in the TOML
+// layout, which can be pulled into alacritty.toml with "import".
+func writeAlacritty(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	fmt.Fprintf(w, "# %s\n", sname)
+
+	for _, section := range alacrittySections(p) {
+		fmt.Fprintf(w, "\n[colors.%s]\n", section.name)
+		for _, key := range section.keys {
+			fmt.Fprintf(w, "%s = %q\n", key, hexColor(section.colors[key]))
+		}
+	}
+
+	return nil
+}
+
+// writeAlacrittyYAML writes the colors as an Alacritty theme in the YAML
+// layout read by releases before 0.13.
+func writeAlacrittyYAML(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	fmt.Fprintf(w, "# %s\n", sname)
+	fmt.Fprintln(w, "colors:")
+
+	for _, section := range alacrittySections(p) {
+		fmt.Fprintf(w, "  %s:\n", section.name)
+		for _, key := range section.keys {
+			fmt.Fprintf(w, "    %s: '%s'\n", key, hexColor(section.colors[key]))
+		}
+	}
+
+	return nil
+}
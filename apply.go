@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryTimeout is how long to wait for the terminal to report its colors
+// before --preview-seconds falls back to resetting them instead.
+const queryTimeout = time.Second
+
+// reColorReply matches the terminal replies to the OSC color queries, as
+// in "\033]4;1;rgb:cdcd/0000/0000\007", ended by BEL or ST.
+var reColorReply = regexp.MustCompile(`\x1b\]((?:4;[0-9]+)|[0-9]+);(rgb:[0-9a-fA-F]+/[0-9a-fA-F]+/[0-9a-fA-F]+)(?:\x07|\x1b\\)`)
+
+// reDeviceAttributes matches the reply to the device attributes request
+// sent after the color queries. Every terminal answers it, so it marks
+// the end of the replies, even from terminals ignoring the queries.
+var reDeviceAttributes = regexp.MustCompile(`\x1b\[\?[0-9;]*c`)
+
+// applyPalette recolors the controlling terminal by writing the OSC
+// sequences straight to it, so stdout is left for the regular output.
+// With a preview time, the colors the terminal had are put back once it's
+// over, or sooner if the context is cancelled, resetting to the terminal
+// defaults the ones it doesn't report.
+func applyPalette(ctx context.Context, p palette, preview time.Duration) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("--apply needs a terminal to recolor, but there's no controlling terminal: %s", err.Error())
+	}
+
+	defer tty.Close()
+
+	seqs := oscSequences(p)
+
+	var saved map[string]string
+	if preview > 0 {
+		saved = queryColors(tty, seqs)
+		debugf("the terminal reported %d of the %d colors to restore", len(saved), len(seqs))
+	}
+
+	var sb strings.Builder
+	for _, seq := range seqs {
+		fmt.Fprintf(&sb, "\033]%s;%s\007", seq.code, seq.color)
+	}
+
+	if _, err := fmt.Fprint(tty, tmuxPassthrough(sb.String())); err != nil {
+		return fmt.Errorf("can't write to the terminal: %s", err.Error())
+	}
+
+	if preview == 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(preview):
+	case <-ctx.Done():
+	}
+
+	debugf("preview is over, restoring the terminal colors")
+
+	if _, err := fmt.Fprint(tty, tmuxPassthrough(restoreSequences(seqs, saved))); err != nil {
+		return fmt.Errorf("can't restore the terminal colors: %s", err.Error())
+	}
+
+	return nil
+}
+
+// queryColors asks the terminal for its current value of each color the
+// sequences set, returning the ones it reports by their OSC code. Any
+// problem, such as a terminal that doesn't answer, only means fewer colors
+// are reported.
+func queryColors(tty *os.File, seqs []oscSequence) map[string]string {
+	restore, err := rawMode(tty)
+	if err != nil {
+		debugf("can't ask the terminal for its colors: %s", err.Error())
+		return nil
+	}
+
+	defer restore()
+
+	var sb strings.Builder
+	for _, seq := range seqs {
+		fmt.Fprintf(&sb, "\033]%s;?\007", seq.code)
+	}
+
+	sb.WriteString("\033[c")
+
+	if _, err := fmt.Fprint(tty, tmuxPassthrough(sb.String())); err != nil {
+		debugf("can't ask the terminal for its colors: %s", err.Error())
+		return nil
+	}
+
+	var replies []byte
+	buf := make([]byte, 1024)
+
+	for deadline := time.Now().Add(queryTimeout); time.Now().Before(deadline) && !reDeviceAttributes.Match(replies); {
+		// the raw mode makes reads return after a tenth of a second
+		// without input, so the deadline is checked again
+		n, err := tty.Read(buf)
+		replies = append(replies, buf[:n]...)
+
+		if err != nil && !errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	return parseColorReplies(replies)
+}
+
+// parseColorReplies returns the colors reported in the replies to the OSC
+// color queries, by their OSC code.
+func parseColorReplies(replies []byte) map[string]string {
+	colors := map[string]string{}
+	for _, m := range reColorReply.FindAllSubmatch(replies, -1) {
+		colors[string(m[1])] = string(m[2])
+	}
+
+	return colors
+}
+
+// restoreSequences returns the sequences putting back the colors the
+// sequences changed, to their saved value or, when the terminal didn't
+// report it, to the terminal default.
+func restoreSequences(seqs []oscSequence, saved map[string]string) string {
+	var sb strings.Builder
+	for _, seq := range seqs {
+		switch color, found := saved[seq.code]; {
+		case found:
+			fmt.Fprintf(&sb, "\033]%s;%s\007", seq.code, color)
+		case strings.HasPrefix(seq.code, "4;"):
+			// OSC 104 resets a palette entry, and OSC 110 and up reset
+			// the dynamic colors set by OSC 10 and up
+			fmt.Fprintf(&sb, "\033]104;%s\007", strings.TrimPrefix(seq.code, "4;"))
+		default:
+			code, _ := strconv.Atoi(seq.code)
+			fmt.Fprintf(&sb, "\033]%d\007", code+100)
+		}
+	}
+
+	return sb.String()
+}
+
+// rawMode turns off the line buffering and echo of the terminal with
+// stty, so the replies to the queries can be read as they come without
+// showing up on screen, and returns a function putting the previous mode
+// back.
+func rawMode(tty *os.File) (func(), error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := stty(tty, "-icanon", "-echo", "min", "0", "time", "1"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if _, err := stty(tty, strings.TrimSpace(saved)); err != nil {
+			debugf("can't restore the terminal mode: %s", err.Error())
+		}
+	}, nil
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s failed: %s", strings.Join(args, " "), err.Error())
+	}
+
+	return string(out), nil
+}
+
+// tmuxPassthrough wraps the sequences so tmux hands them over to the
+// terminal it runs in, rather than swallowing them, when running inside
+// tmux. Each escape character is doubled, as tmux expects.
+func tmuxPassthrough(seqs string) string {
+	if os.Getenv("TMUX") == "" {
+		return seqs
+	}
+
+	return "\033Ptmux;" + strings.ReplaceAll(seqs, "\033", "\033\033") + "\033\\"
+}
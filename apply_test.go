@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseColorReplies(t *testing.T) {
+	// as xterm answers, palette entries ended by BEL and the foreground by
+	// ST, followed by the device attributes
+	replies := "\033]4;1;rgb:cdcd/0000/0000\007\033]4;15;rgb:ffff/ffff/ffff\007\033]10;rgb:c5c5/c8c8/c6c6\033\\\033[?64;1;2;6c"
+
+	got := parseColorReplies([]byte(replies))
+	want := map[string]string{
+		"4;1":  "rgb:cdcd/0000/0000",
+		"4;15": "rgb:ffff/ffff/ffff",
+		"10":   "rgb:c5c5/c8c8/c6c6",
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("parseColorReplies() = %q, want %q", got, want)
+	}
+
+	for code, color := range want {
+		if got[code] != color {
+			t.Errorf("color %s = %q, want %q", code, got[code], color)
+		}
+	}
+
+	if got := parseColorReplies([]byte("\033[?1;2c")); len(got) != 0 {
+		t.Errorf("parseColorReplies() = %q from a terminal ignoring the queries, want nothing", got)
+	}
+}
+
+func TestRestoreSequences(t *testing.T) {
+	seqs := []oscSequence{
+		{code: "4;1", color: "#cc6666"},
+		{code: "4;2", color: "#b5bd68"},
+		{code: "10", color: "#c5c8c6"},
+		{code: "11", color: "#1d1f21"},
+	}
+
+	saved := map[string]string{
+		"4;1": "rgb:cdcd/0000/0000",
+		"10":  "rgb:0000/0000/0000",
+	}
+
+	want := "\033]4;1;rgb:cdcd/0000/0000\007" +
+		"\033]104;2\007" +
+		"\033]10;rgb:0000/0000/0000\007" +
+		"\033]111\007"
+
+	if got := restoreSequences(seqs, saved); got != want {
+		t.Errorf("restoreSequences() = %q, want %q", got, want)
+	}
+}
+
+func TestOSCSequences(t *testing.T) {
+	seqs := oscSequences(builtinTestPalette(t, "gruvbox-dark"))
+
+	if len(seqs) != 19 {
+		t.Fatalf("got %d sequences, want the 16 palette entries plus foreground, background and cursor", len(seqs))
+	}
+
+	for i, want := range map[int]oscSequence{
+		0:  {code: "4;0", color: "#282828"},
+		15: {code: "4;15", color: "#ebdbb2"},
+		16: {code: "10", color: "#ebdbb2"},
+		17: {code: "11", color: "#282828"},
+		18: {code: "12", color: "#ebdbb2"},
+	} {
+		if seqs[i] != want {
+			t.Errorf("sequence %d = %+v, want %+v", i, seqs[i], want)
+		}
+	}
+}
+
+func TestTmuxPassthrough(t *testing.T) {
+	seqs := "\033]4;1;#cc6666\007\033]10;#c5c8c6\007"
+
+	t.Setenv("TMUX", "")
+	if got := tmuxPassthrough(seqs); got != seqs {
+		t.Errorf("tmuxPassthrough() = %q outside of tmux, want the sequences unchanged", got)
+	}
+
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1234,0")
+	want := "\033Ptmux;\033\033]4;1;#cc6666\007\033\033]10;#c5c8c6\007\033\\"
+	if got := tmuxPassthrough(seqs); got != want {
+		t.Errorf("tmuxPassthrough() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const maxEntrySize = 1 << 20
+
+// archiveEntry is a regular file read out of an archive.
+type archiveEntry struct {
+	name string
+	data []byte
+}
+
+// isArchive reports whether the input name is a zip or tar archive, by
+// its extension.
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readArchive returns the regular files in a zip, tar or gzipped tar
+// archive, read from disk or downloaded. Entries larger than maxEntrySize
+// can't be theme files, so they are skipped with a warning and only
+// counted.
+func readArchive(fname string) ([]archiveEntry, int, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if isURL(fname) {
+		var file remoteFile
+		file, err = download(fname, cacheRevalidate)
+		data = file.Body
+	} else {
+		data, err = os.ReadFile(fname)
+	}
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("can't open archive %q: %s", fname, err.Error())
+	}
+
+	var entries []archiveEntry
+	oversized := 0
+
+	add := func(name string, size int64, r io.Reader) error {
+		if size > maxEntrySize {
+			warnf("%s: skipping %s, it's larger than the %d KiB limit for theme files", fname, name, maxEntrySize>>10)
+			oversized++
+			return nil
+		}
+
+		var b bytes.Buffer
+		if _, err := io.Copy(&b, io.LimitReader(r, maxEntrySize)); err != nil {
+			return fmt.Errorf("can't read %s from archive %q: %s", name, fname, err.Error())
+		}
+
+		entries = append(entries, archiveEntry{name: name, data: b.Bytes()})
+		return nil
+	}
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid zip archive %q: %s", fname, err.Error())
+		}
+
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+
+			rc, err := f.Open()
+			if err != nil {
+				return nil, 0, fmt.Errorf("can't read %s from archive %q: %s", f.Name, fname, err.Error())
+			}
+
+			err = add(f.Name, int64(f.UncompressedSize64), rc)
+			rc.Close()
+
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+
+		return entries, oversized, nil
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid gzip archive %q: %s", fname, err.Error())
+		}
+
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid tar archive %q: %s", fname, err.Error())
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := add(hdr.Name, hdr.Size, tr); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return entries, oversized, nil
+}
+
+// entrySource is the name reported for lines read from an archive entry.
+func entrySource(archive, entry string) string {
+	return archive + ":" + entry
+}
+
+// readArchiveMember returns the lines of a single archive entry.
+func readArchiveMember(archive, member string) ([]sourceLine, error) {
+	entries, _, err := readArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.name == member || strings.TrimPrefix(e.name, "./") == member {
+			return scanLines(entrySource(archive, e.name), bytes.NewReader(e.data))
+		}
+
+		names = append(names, e.name)
+	}
+
+	if len(names) > 10 {
+		names = append(names[:10], fmt.Sprintf("and %d more", len(names)-10))
+	}
+
+	return nil, fmt.Errorf("no entry named %q in archive %q, it has: %s", member, archive, strings.Join(names, ", "))
+}
+
+// decodeMember decodes a single archive entry, which is named after its
+// path when the format has no theme name.
+func decodeMember(archive, member, from string, opts decodeOptions) (map[string]resource, string, error) {
+	lines, err := readArchiveMember(archive, member)
+	if err != nil {
+		return nil, "", withCode(exitInput, err)
+	}
+
+	values, name, err := decodeLines(entrySource(archive, member), lines, from, opts)
+	if name == "" {
+		name = sanitizeSessionName(entrySessionName(member))
+	}
+
+	return values, name, err
+}
+
+// entrySessionName names the session for an archive entry after its path
+// without the extension.
+func entrySessionName(name string) string {
+	name = strings.TrimPrefix(name, "./")
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// convertArchive converts every entry of the archive that looks like a
+// color scheme into a file in the output directory. Entries that can't be
+// detected or converted are skipped, and a summary is printed at the end.
+func convertArchive(archive string, bt *batch) error {
+	entries, skipped, err := readArchive(archive)
+	if err != nil {
+		return err
+	}
+
+	converted := 0
+
+	for _, e := range entries {
+		source := entrySource(archive, e.name)
+
+		lines, err := scanLines(source, bytes.NewReader(e.data))
+		if err != nil {
+			warnf("skipping %s: %s", e.name, err.Error())
+			skipped++
+			continue
+		}
+
+		// blank files, such as an empty README, would read as Xresources
+		// without any colors
+		if isBlank(lines) {
+			debugf("skipping %s, it only has blank lines and comments", e.name)
+			skipped++
+			continue
+		}
+
+		if bt.from == "" {
+			if _, err := findInputFormat("", lines); err != nil {
+				debugf("skipping %s, it doesn't look like a color scheme", e.name)
+				skipped++
+				continue
+			}
+		}
+
+		path, err := bt.convertToFile(source, lines, entrySessionName(e.name), "")
+		if err != nil {
+			warnf("skipping %s: %s", e.name, err.Error())
+			skipped++
+			continue
+		}
+
+		debugf("converted %s to %s", e.name, path)
+		converted++
+	}
+
+	notef("converted %d of %d entries, %d skipped", converted, converted+skipped, skipped)
+
+	if converted == 0 {
+		return errors.New("none of the archive entries could be converted")
+	}
+
+	return bt.finish(path.Base(archive))
+}
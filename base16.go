@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// reBase16Line matches a "key: value" line of a Base16 scheme. Quoted
+// values can start with "#", as in the tinted-theming layout, and a "#"
+// only starts a comment after whitespace.
+var reBase16Line = regexp.MustCompile(`^[ \t]*(scheme|name|author|base0[0-9A-Fa-f])[ \t]*:[ \t]*["']?(#?[^"'#]*?)["']?(?:[ \t]+#.*)?[ \t]*$`)
+
+// base16Mapping is the standard Base16 terminal mapping, as used by
+// base16-shell and base16-xresources, from Xresources keys to the Base16
+// slots they take their color from.
+var base16Mapping = map[string]string{
+	"foreground":  "base05",
+	"background":  "base00",
+	"cursorColor": "base05",
+	"color0":      "base00",
+	"color1":      "base08",
+	"color2":      "base0B",
+	"color3":      "base0A",
+	"color4":      "base0D",
+	"color5":      "base0E",
+	"color6":      "base0C",
+	"color7":      "base05",
+	"color8":      "base03",
+	"color9":      "base08",
+	"color10":     "base0B",
+	"color11":     "base0A",
+	"color12":     "base0D",
+	"color13":     "base0E",
+	"color14":     "base0C",
+	"color15":     "base07",
+}
+
+func detectBase16(content string) bool {
+	return strings.Contains(content, "base00:") && (strings.Contains(content, "scheme:") || strings.Contains(content, "name:"))
+}
+
+// parseBase16Fields reads the "key: value" pairs of a Base16 scheme,
+// supporting both the classic flat layout and the newer one where the
+// colors are nested under "palette:".
+func parseBase16Fields(lines []sourceLine) map[string]resource {
+	fields := map[string]resource{}
+
+	for _, line := range lines {
+		m := reBase16Line.FindStringSubmatch(line.text)
+		if m == nil {
+			continue
+		}
+
+		key := m[1]
+		if strings.HasPrefix(key, "base") {
+			key = "base" + strings.ToUpper(key[4:])
+		}
+
+		fields[key] = resource{value: strings.TrimSpace(m[2]), source: line}
+	}
+
+	return fields
+}
+
+// parseBase16 reads a Base16 YAML scheme, whose values are hex colors
+// without the leading "#".
+func parseBase16(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	fields := parseBase16Fields(lines)
+	values := map[string]resource{}
+
+	for key, slot := range base16Mapping {
+		res, found := fields[slot]
+		if !found {
+			continue
+		}
+
+		if !strings.HasPrefix(res.value, "#") {
+			res.value = "#" + res.value
+		}
+
+		values[key] = res
+	}
+
+	if len(values) != 0 && len(values) != len(base16Mapping) {
+		missing := make([]string, 0, 16)
+		for i := 0; i < 16; i++ {
+			slot := fmt.Sprintf("base%02X", i)
+			if _, found := fields[slot]; !found {
+				missing = append(missing, slot)
+			}
+		}
+
+		return nil, fmt.Errorf("the following Base16 slots weren't found: %s", strings.Join(missing, ", "))
+	}
+
+	return values, nil
+}
+
+// base16Name returns the scheme name of a Base16 scheme.
+func base16Name(lines []sourceLine, _ decodeOptions) string {
+	fields := parseBase16Fields(lines)
+	if res, found := fields["scheme"]; found {
+		return res.value
+	}
+
+	return fields["name"].value
+}
+
+// base16Derivation documents, in the output, how the Base16 slots are
+// derived from the terminal colors, since only some are a direct match.
+const base16Derivation = `# Base16 slots taken from the terminal colors:
+#   base00 background, base03 color8, base05 foreground, base07 color15
+#   base08 color1, base0A color3, base0B color2, base0C color6, base0D color4, base0E color5
+# and the ones ANSI has no equivalent for, blended from those:
+#   base01 and base02 at a third and two thirds from base00 to base03
+#   base04 halfway from base03 to base05, base06 halfway from base05 to base07
+#   base09 halfway from color1 to color3, base0F color1 a third of the way to base00`
+
+// writeBase16 writes the colors as a Base16 YAML scheme, reversing the
+// standard terminal mapping in base16Mapping.
+func writeBase16(w io.Writer, sname string, p palette, opts encodeOptions) error {
+	c := func(key string) color.RGBA {
+		return p.keys[key]
+	}
+
+	slots := []color.RGBA{
+		c("background"),
+		mixColors(c("background"), c("color8"), 1.0/3),
+		mixColors(c("background"), c("color8"), 2.0/3),
+		c("color8"),
+		mixColors(c("color8"), c("foreground"), 0.5),
+		c("foreground"),
+		mixColors(c("foreground"), c("color15"), 0.5),
+		c("color15"),
+		c("color1"),
+		mixColors(c("color1"), c("color3"), 0.5),
+		c("color3"),
+		c("color2"),
+		c("color6"),
+		c("color4"),
+		c("color5"),
+		mixColors(c("color1"), c("background"), 1.0/3),
+	}
+
+	fmt.Fprintln(w, base16Derivation)
+	fmt.Fprintf(w, "scheme: %q\n", sname)
+	fmt.Fprintf(w, "author: %q\n", opts.author)
+
+	for i, slot := range slots {
+		fmt.Fprintf(w, "base%02X: %q\n", i, strings.TrimPrefix(hexColor(slot), "#"))
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseBase16(t *testing.T) {
+	for _, name := range []string{"tomorrow-night.base16.yaml", "tomorrow-night.tinted.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			values := readTestValues(t, name)
+
+			if got := values["foreground"].format; got != "base16" {
+				t.Fatalf("read as %q, want base16", got)
+			}
+
+			for key, value := range map[string]string{
+				"foreground":  "#c5c8c6",
+				"background":  "#1d1f21",
+				"cursorColor": "#c5c8c6",
+				"color0":      "#1d1f21",
+				"color1":      "#cc6666",
+				"color8":      "#969896",
+				"color12":     "#81a2be",
+				"color15":     "#ffffff",
+			} {
+				if got := values[key].value; got != value {
+					t.Errorf("%s = %q, want %q", key, got, value)
+				}
+			}
+
+			if _, err := convert(values); err != nil {
+				t.Errorf("convert() = %s", err)
+			}
+
+			lines, err := readLines("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := base16Name(lines, decodeOptions{}); got != "Tomorrow Night" {
+				t.Errorf("base16Name() = %q, want Tomorrow Night", got)
+			}
+		})
+	}
+}
+
+func TestBase16Line(t *testing.T) {
+	tests := []struct {
+		line  string
+		value string
+	}{
+		{`base00: "1d1f21"`, "1d1f21"},
+		{`base00: "#1d1f21"`, "#1d1f21"},
+		{`  base00: '#1d1f21'  # background`, "#1d1f21"},
+		{`base00: 1d1f21 # background`, "1d1f21"},
+		{`base00: 1d1f21#notacomment`, ""},
+		{`scheme: "Tomorrow Night"`, "Tomorrow Night"},
+	}
+
+	for _, tt := range tests {
+		m := reBase16Line.FindStringSubmatch(tt.line)
+		switch {
+		case m == nil && tt.value != "":
+			t.Errorf("%q doesn't match, want %q", tt.line, tt.value)
+		case m != nil && m[2] != tt.value:
+			t.Errorf("%q = %q, want %q", tt.line, m[2], tt.value)
+		}
+	}
+}
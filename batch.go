@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// batch holds the settings of the modes converting many themes at once,
+// each into its own file in outDir.
+type batch struct {
+	outDir string
+	from   string
+	opts   decodeOptions
+	to     outputFormat
+
+	// used tracks the session names already written, in lowercase, along
+	// with the input each came from.
+	used map[string]string
+
+	// unique makes a session name that's already used an error naming
+	// both inputs, instead of numbering the second one.
+	unique bool
+
+	// themes are the themes converted so far, when the output format
+	// writes them all into a single page, or into the combined file.
+	themes []namedPalette
+
+	// combined, when set, is the file all the themes are written to
+	// instead. Existing files, combined or not, are replaced only when
+	// force is set, and saved first when backup is set.
+	combined      string
+	force, backup bool
+}
+
+func newBatch(outDir, from string, opts decodeOptions, to outputFormat) (*batch, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("can't create output directory: %s", err.Error())
+	}
+
+	return &batch{outDir: outDir, from: from, opts: opts, to: to, used: map[string]string{}}, nil
+}
+
+// convertToFile converts the lines of a single input into a file in the
+// output directory. The session is named after name, or else after the
+// theme name stored in the input, if any, or else after fallback. Names
+// already used get a numbered suffix, so themes with the same name don't
+// overwrite each other.
+func (bt *batch) convertToFile(fname string, lines []sourceLine, name, fallback string) (string, error) {
+	values, themeName, err := decodeLines(fname, lines, bt.from, bt.opts)
+	if err != nil {
+		return "", err
+	}
+
+	if len(values) == 0 {
+		return "", errors.New("no color codes found")
+	}
+
+	p, err := convert(values)
+	if err != nil {
+		return "", err
+	}
+
+	sname := sanitizeSessionName(name)
+	if sname == "" {
+		sname = sanitizeSessionName(themeName)
+	}
+
+	if sname == "" {
+		sname = sanitizeSessionName(fallback)
+	}
+
+	if sname == "" {
+		return "", errors.New("no name to give the session")
+	}
+
+	if prev, found := bt.used[strings.ToLower(sname)]; found && bt.unique {
+		return "", fmt.Errorf("session %q is already written for %s", sname, prev)
+	}
+
+	for base, n := sname, 2; bt.used[strings.ToLower(sname)] != ""; n++ {
+		sname = fmt.Sprintf("%s %d", base, n)
+	}
+
+	if bt.to.isSessionFormat() {
+		if err := validateSessionName(sname); err != nil {
+			return "", err
+		}
+	}
+
+	bt.used[strings.ToLower(sname)] = fname
+
+	if bt.to.page != nil || bt.combined != "" {
+		bt.themes = append(bt.themes, namedPalette{name: sname, palette: p})
+		return bt.pagePath(), nil
+	}
+
+	var b bytes.Buffer
+	if err := bt.to.encode(&b, sname, p); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(bt.outDir, bt.to.outputFileName(sname))
+	if err := writeOutputFile(path, b.Bytes(), bt.force, bt.backup); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// pagePath returns the path of the single page written by formats that
+// combine all the themes of a batch, or of the --combined file.
+func (bt *batch) pagePath() string {
+	if bt.combined != "" {
+		return bt.combined
+	}
+
+	return filepath.Join(bt.outDir, bt.to.pageName+bt.to.ext)
+}
+
+// finish writes the themes converted by the batch into a single page, for
+// the output formats that combine them, or into the --combined file. It
+// does nothing for the others.
+func (bt *batch) finish(title string) error {
+	if (bt.to.page == nil && bt.combined == "") || len(bt.themes) == 0 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	if bt.combined != "" && bt.to.combine != nil {
+		if err := bt.to.combine(&b, bt.themes, bt.to.opts); err != nil {
+			return err
+		}
+
+	} else if err := bt.to.page(&b, title, bt.themes, bt.to.opts); err != nil {
+		return err
+	}
+
+	data := b.Bytes()
+	if bt.to.combine != nil && bt.to.opts.utf16 {
+		data = encodeUTF16(b.String())
+	}
+
+	path := bt.pagePath()
+	if err := writeOutputFile(path, data, bt.force, bt.backup); err != nil {
+		return err
+	}
+
+	debugf("wrote %d themes to %s", len(bt.themes), path)
+	return nil
+}
+
+// canCombine reports whether the format can write all the themes of a
+// batch into a single file.
+func canCombine(to outputFormat) bool {
+	return to.page != nil || to.combine != nil
+}
+
+// isBatchInput reports whether the input names a directory, or is a glob
+// pattern rather than an existing file, for convertFiles to convert every
+// theme in it.
+func isBatchInput(fname string) bool {
+	if fname == "-" || isURL(fname) || strings.HasPrefix(fname, builtinPrefix) {
+		return false
+	}
+
+	info, err := os.Stat(fname)
+	if err == nil {
+		return info.IsDir()
+	}
+
+	return strings.ContainsAny(fname, "*?[")
+}
+
+// batchFiles returns the files of a directory or matching a glob pattern,
+// sorted by name. Hidden files and subdirectories are left out.
+func batchFiles(input string) ([]string, error) {
+	var matches []string
+
+	if info, err := os.Stat(input); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(input)
+		if err != nil {
+			return nil, withCode(exitInput, fmt.Errorf("can't read the directory %q: %s", input, err.Error()))
+		}
+
+		for _, e := range entries {
+			matches = append(matches, filepath.Join(input, e.Name()))
+		}
+	} else {
+		if matches, err = filepath.Glob(input); err != nil {
+			return nil, withCode(exitUsage, fmt.Errorf("invalid pattern %q: %s", input, err.Error()))
+		}
+	}
+
+	var files []string
+	for _, m := range matches {
+		if strings.HasPrefix(filepath.Base(m), ".") {
+			continue
+		}
+
+		if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+			files = append(files, m)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, withCode(exitInput, fmt.Errorf("no files found in %q", input))
+	}
+
+	return files, nil
+}
+
+// convertFiles converts every file of a directory or glob pattern, each
+// into a session named by the template after the file. Files that don't
+// look like a color scheme are skipped, and the ones that fail to convert,
+// or that get a session name already used, are reported and skipped too,
+// failing the batch only when none could be converted or when strict is
+// set.
+func convertFiles(input string, bt *batch, names *nameTemplate, strict bool) error {
+	fnames, err := batchFiles(input)
+	if err != nil {
+		return err
+	}
+
+	var converted, skipped, failed int
+
+	bt.unique = true
+
+	for i, fname := range fnames {
+		lines, err := readLines(fname)
+		if err != nil {
+			warnf("skipping %s: %s", fname, err.Error())
+			failed++
+			continue
+		}
+
+		// blank files, such as an empty README, would read as Xresources
+		// without any colors
+		if isBlank(lines) {
+			debugf("skipping %s, it only has blank lines and comments", fname)
+			skipped++
+			continue
+		}
+
+		if bt.from == "" {
+			if _, err := findInputFormat("", lines); err != nil {
+				debugf("skipping %s, it doesn't look like a color scheme", fname)
+				skipped++
+				continue
+			}
+		}
+
+		sname := names.name(fname, i+1)
+		if sanitizeSessionName(sname) == "" {
+			warnf("skipping %s: the name template gives it an empty session name", fname)
+			failed++
+			continue
+		}
+
+		path, err := bt.convertToFile(fname, lines, sname, "")
+		if err != nil {
+			warnf("skipping %s: %s", fname, err.Error())
+			failed++
+			continue
+		}
+
+		debugf("converted %s to %s", fname, path)
+		converted++
+	}
+
+	notef("converted %d of %d files, %d skipped as they aren't color schemes, %d failed", converted, len(fnames), skipped, failed)
+
+	switch {
+	case converted == 0:
+		return withCode(exitParse, fmt.Errorf("none of the files in %q could be converted", input))
+	case strict && failed > 0:
+		return withCode(exitParse, fmt.Errorf("%d of the files in %q failed to convert", failed, input))
+	}
+
+	return bt.finish(filepath.Base(strings.TrimRight(input, `/\`)))
+}
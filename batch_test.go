@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyTheme copies a file from testdata into dir, under the given name.
+func copyTheme(t *testing.T, name, dir, as string) {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, dir, as, string(b))
+}
+
+func TestConvertFilesSkipsBlank(t *testing.T) {
+	withFlags(t)
+
+	dir, outDir := t.TempDir(), t.TempDir()
+	copyTheme(t, "mixed-case.Xresources", dir, "tomorrow.Xresources")
+	writeTestFile(t, dir, "README", "")
+	writeTestFile(t, dir, "notes.Xresources", "! the bright colors still need some work\n\n")
+
+	if err := run([]string{dir, "--strict", "--out-dir", outDir, "--to", "xresources"}); err != nil {
+		t.Fatalf("run() = %s, want the blank files skipped rather than failed", err)
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "tomorrow.Xresources" {
+		t.Errorf("wrote %d files, want only tomorrow.Xresources", len(entries))
+	}
+}
+
+func TestConvertArchiveSkipsBlank(t *testing.T) {
+	withFlags(t)
+
+	theme, err := os.ReadFile(filepath.Join("testdata", "mixed-case.Xresources"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "themes.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"themes/README":              "",
+		"themes/notes.Xresources":    "! nothing here yet\n",
+		"themes/tomorrow.Xresources": string(theme),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write([]byte(content))
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Close()
+
+	outDir := t.TempDir()
+	var runErr error
+	stderr := captureStderr(t, func() {
+		runErr = run([]string{archive, "--all", "--out-dir", outDir, "--to", "xresources"})
+	})
+
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	if want := "converted 1 of 3 entries, 2 skipped"; !strings.Contains(stderr, want) {
+		t.Errorf("reported %q, want %q", stderr, want)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// cachePath returns where the download cache keeps its copy of the URL.
+func cachePath(rawURL string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, "urxvt-kitty", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// readCache returns the cached copy of the URL, if there's one. Since the
+// cache is only there to save downloads, any problem reading it is treated
+// as a cache miss.
+func readCache(rawURL string) (remoteFile, bool) {
+	if fetchOpts.noCache {
+		return remoteFile{}, false
+	}
+
+	path, err := cachePath(rawURL)
+	if err != nil {
+		debugf("not using the download cache: %s", err.Error())
+		return remoteFile{}, false
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			debugf("can't read the cached copy of %s: %s", rawURL, err.Error())
+		}
+
+		return remoteFile{}, false
+	}
+
+	var file remoteFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		debugf("ignoring the corrupt cached copy of %s: %s", rawURL, err.Error())
+		return remoteFile{}, false
+	}
+
+	return file, true
+}
+
+// writeCache stores the downloaded file in the cache. Failures are only
+// reported with --verbose.
+func writeCache(rawURL string, file remoteFile) {
+	if fetchOpts.noCache {
+		return
+	}
+
+	path, err := cachePath(rawURL)
+	if err != nil {
+		debugf("not using the download cache: %s", err.Error())
+		return
+	}
+
+	b, err := json.Marshal(file)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+
+	if err == nil {
+		err = os.WriteFile(path, b, 0644)
+	}
+
+	if err != nil {
+		debugf("can't cache the downloaded copy of %s: %s", rawURL, err.Error())
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// themeServer serves a theme that can be changed by the test, answering
+// conditional requests with 304 while it's unchanged. It counts the
+// requests it got, and the ones that were answered with 304.
+type themeServer struct {
+	*httptest.Server
+	body                  atomic.Value
+	requests, notModified int32
+}
+
+func newThemeServer(t *testing.T, body string) *themeServer {
+	t.Helper()
+
+	ts := &themeServer{}
+	ts.body.Store(body)
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ts.requests, 1)
+
+		body := ts.body.Load().(string)
+		etag := fmt.Sprintf(`"%08x"`, crc32.ChecksumIEEE([]byte(body)))
+
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&ts.notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body))
+	}))
+
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// withCache points the download cache to an empty directory for the
+// test.
+func withCache(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	prev := fetchOpts
+	fetchOpts = fetchOptions{timeout: 5 * time.Second}
+	t.Cleanup(func() { fetchOpts = prev })
+}
+
+func TestCacheRevalidate(t *testing.T) {
+	withCache(t)
+	ts := newThemeServer(t, "*.foreground: #c5c8c6\n")
+	url := ts.URL + "/theme.Xresources"
+
+	for i := 0; i < 2; i++ {
+		if _, err := download(url, cacheRevalidate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ts.requests != 2 || ts.notModified != 1 {
+		t.Errorf("got %d requests, %d of them not modified, want the second one to revalidate the cached copy", ts.requests, ts.notModified)
+	}
+
+	ts.body.Store("*.foreground: #ffffff\n")
+
+	file, err := download(url, cacheRevalidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(file.Body) != "*.foreground: #ffffff\n" {
+		t.Errorf("download() = %q, want the changed theme", file.Body)
+	}
+}
+
+func TestCacheReuse(t *testing.T) {
+	withCache(t)
+	ts := newThemeServer(t, "*.foreground: #c5c8c6\n")
+	url := ts.URL + "/theme.Xresources"
+
+	for i := 0; i < 2; i++ {
+		if _, err := download(url, cacheReuse); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ts.requests != 1 {
+		t.Errorf("got %d requests, want the cached copy reused without asking", ts.requests)
+	}
+
+	fetchOpts.refresh = true
+	if _, err := download(url, cacheReuse); err != nil {
+		t.Fatal(err)
+	}
+
+	if ts.requests != 2 || ts.notModified != 1 {
+		t.Errorf("got %d requests, %d of them not modified, want --refresh to revalidate", ts.requests, ts.notModified)
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	withCache(t)
+	fetchOpts.noCache = true
+
+	ts := newThemeServer(t, "*.foreground: #c5c8c6\n")
+	for i := 0; i < 2; i++ {
+		if _, err := download(ts.URL+"/theme.Xresources", cacheReuse); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ts.requests != 2 || ts.notModified != 0 {
+		t.Errorf("got %d requests, %d of them not modified, want two full downloads", ts.requests, ts.notModified)
+	}
+}
+
+func TestExpandGitHub(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"github:mbadolato/iTerm2-Color-Schemes/schemes/Dracula.itermcolors", "https://raw.githubusercontent.com/mbadolato/iTerm2-Color-Schemes/HEAD/schemes/Dracula.itermcolors", true},
+		{"github:chriskempson/base16-schemes/ocean.yaml@v1.0", "https://raw.githubusercontent.com/chriskempson/base16-schemes/v1.0/ocean.yaml", true},
+		{"github:owner/repo", "", false},
+		{"github:owner/repo/file@", "", false},
+		{"themes/nord.Xresources", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := expandGitHub(tt.name)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("expandGitHub(%q) = %q, %t, want %q, %t", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCacheSkip(t *testing.T) {
+	withCache(t)
+	ts := newThemeServer(t, "<a href=\"/dots/1/\">Ocean</a>\n")
+	url := ts.URL + "/themes/"
+
+	for i := 0; i < 2; i++ {
+		if _, err := download(url, cacheSkip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ts.requests != 2 || ts.notModified != 0 {
+		t.Errorf("got %d requests, %d of them not modified, want the listing downloaded again", ts.requests, ts.notModified)
+	}
+
+	if _, err := download(url, cacheRevalidate); err != nil {
+		t.Fatal(err)
+	}
+
+	if ts.notModified != 0 {
+		t.Errorf("got %d requests not modified, want nothing cached by the skipped downloads", ts.notModified)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidHex = errors.New("invalid hex color, expecting #rgb, #rrggbb or #rrrrggggbbbb")
+	errAlphaHex   = errors.New("invalid hex color, 8-digit #rrggbbaa values with an alpha channel are not supported by KiTTY, drop the last two digits")
+)
+
+// parseColor parses a color in any of the notations supported by the
+// input formats: hex, X11 "rgb:", decimal "r,g,b" triplets, either bare or
+// in the CSS "rgb(r,g,b)" notation, or X11 names.
+func parseColor(s string) (color.RGBA, error) {
+	switch {
+	case strings.HasPrefix(s, "#"):
+		return hexToRGB(s)
+	case strings.HasPrefix(s, "rgb:"):
+		return x11ToRGB(s)
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		return decimalToRGB(s[4 : len(s)-1])
+	case strings.Contains(s, ","):
+		return decimalToRGB(s)
+	}
+
+	return namedToRGB(s)
+}
+
+// hexColor formats a color in the lowercase "#rrggbb" notation.
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// bgrDword formats a color as the 0x00BBGGRR dword used by Windows
+// programs, as eight hex digits.
+func bgrDword(c color.RGBA) string {
+	return fmt.Sprintf("00%02x%02x%02x", c.B, c.G, c.R)
+}
+
+// colorDistance returns the squared euclidean distance between two
+// colors, enough to compare how close they are.
+func colorDistance(a, b color.RGBA) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// nearestColor returns the index of the candidate closest to c, the
+// lowest one winning ties.
+func nearestColor(c color.RGBA, candidates []color.RGBA) int {
+	best := 0
+	for i, candidate := range candidates {
+		if colorDistance(c, candidate) < colorDistance(c, candidates[best]) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// isLight reports whether text on top of the color reads better in black
+// than in white, going by its perceived brightness.
+func isLight(c color.RGBA) bool {
+	return 299*int(c.R)+587*int(c.G)+114*int(c.B) > 128000
+}
+
+// mixColors blends a towards b, t being the share of b between 0 and 1.
+func mixColors(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t))
+	}
+
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 0xff}
+}
+
+// dropAlpha removes the alpha channel from "#rrggbbaa" and
+// "rgba(r, g, b, a)" values, the latter becoming "r, g, b", reporting
+// whether it did. Other values are returned unchanged.
+func dropAlpha(s string) (string, bool) {
+	if len(s) == 9 && strings.HasPrefix(s, "#") {
+		return s[:7], true
+	}
+
+	if !strings.HasPrefix(s, "rgba(") || !strings.HasSuffix(s, ")") {
+		return s, false
+	}
+
+	channels := strings.Split(s[5:len(s)-1], ",")
+	if len(channels) != 4 {
+		return s, false
+	}
+
+	return strings.Join(channels[:3], ","), true
+}
+
+// decimalToRGB parses colors written as decimal "r,g,b" triplets, each
+// channel between 0 and 255.
+func decimalToRGB(s string) (c color.RGBA, err error) {
+	c.A = 0xff
+
+	channels := strings.Split(s, ",")
+	if len(channels) != 3 {
+		return c, fmt.Errorf("invalid decimal color %q, expecting r,g,b", s)
+	}
+
+	values := make([]uint8, 0, 3)
+	for _, ch := range channels {
+		v, err := strconv.Atoi(strings.TrimSpace(ch))
+		if err != nil || v < 0 || v > 255 {
+			return c, fmt.Errorf("invalid decimal color %q, channel %q must be a number between 0 and 255", s, strings.TrimSpace(ch))
+		}
+
+		values = append(values, uint8(v))
+	}
+
+	c.R, c.G, c.B = values[0], values[1], values[2]
+	return c, nil
+}
+
+// namedToRGB resolves X11 color names such as "DarkSlateGray" or
+// "dark slate gray", ignoring case and internal spaces.
+func namedToRGB(s string) (color.RGBA, error) {
+	name := strings.ToLower(strings.ReplaceAll(s, " ", ""))
+
+	c, found := x11ColorNames[name]
+	if !found {
+		return c, errors.New("unknown X11 color name")
+	}
+
+	return c, nil
+}
+
+// x11ToRGB parses colors in the X11 "rgb:r/g/b" notation, where each
+// channel has between 1 and 4 hex digits, scaling them down to 8 bits.
+func x11ToRGB(s string) (c color.RGBA, err error) {
+	c.A = 0xff
+
+	channels := strings.Split(strings.TrimPrefix(s, "rgb:"), "/")
+	if len(channels) != 3 {
+		return c, errors.New("invalid X11 color, expecting rgb:r/g/b")
+	}
+
+	values := make([]uint8, 0, 3)
+	for _, ch := range channels {
+		if len(ch) < 1 || len(ch) > 4 {
+			return c, fmt.Errorf("invalid X11 color, channel %q must have between 1 and 4 hex digits", ch)
+		}
+
+		v, err := strconv.ParseUint(ch, 16, 16)
+		if err != nil {
+			return c, fmt.Errorf("invalid X11 color, channel %q is not hexadecimal", ch)
+		}
+
+		limit := uint64(1)<<(4*len(ch)) - 1
+		values = append(values, uint8((v*0xff+limit/2)/limit))
+	}
+
+	c.R, c.G, c.B = values[0], values[1], values[2]
+	return c, nil
+}
+
+// hexToRGB parses colors in the "#rrggbb" notation, the shorthand "#rgb"
+// notation, where each digit of the shorthand is repeated to form a byte,
+// and the 16-bit per channel "#rrrrggggbbbb" notation, rounded to 8 bits.
+func hexToRGB(s string) (c color.RGBA, err error) {
+	c.A = 0xff
+
+	if len(s) == 0 || s[0] != '#' {
+		return c, errInvalidHex
+	}
+
+	hexToByte := func(b byte) byte {
+		switch {
+		case b >= '0' && b <= '9':
+			return b - '0'
+		case b >= 'a' && b <= 'f':
+			return b - 'a' + 10
+		case b >= 'A' && b <= 'F':
+			return b - 'A' + 10
+		}
+		err = errInvalidHex
+		return 0
+	}
+
+	switch len(s) {
+	case 7:
+		c.R = hexToByte(s[1])<<4 + hexToByte(s[2])
+		c.G = hexToByte(s[3])<<4 + hexToByte(s[4])
+		c.B = hexToByte(s[5])<<4 + hexToByte(s[6])
+	case 4:
+		c.R = hexToByte(s[1]) * 17
+		c.G = hexToByte(s[2]) * 17
+		c.B = hexToByte(s[3]) * 17
+	case 13:
+		word := func(s string) uint8 {
+			v := uint32(hexToByte(s[0]))<<12 | uint32(hexToByte(s[1]))<<8 | uint32(hexToByte(s[2]))<<4 | uint32(hexToByte(s[3]))
+			return uint8((v*0xff + 0x7fff) / 0xffff)
+		}
+		c.R, c.G, c.B = word(s[1:5]), word(s[5:9]), word(s[9:13])
+	case 9:
+		err = errAlphaHex
+	default:
+		err = errInvalidHex
+	}
+	return
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestHexToRGB(t *testing.T) {
+	tests := []struct {
+		hex     string
+		want    color.RGBA
+		wantErr error
+	}{
+		{"#fff", color.RGBA{0xff, 0xff, 0xff, 0xff}, nil},
+		{"#000", color.RGBA{0, 0, 0, 0xff}, nil},
+		{"#f80", color.RGBA{0xff, 0x88, 0x00, 0xff}, nil},
+		{"#ABC", color.RGBA{0xaa, 0xbb, 0xcc, 0xff}, nil},
+		{"#1d1f21", color.RGBA{0x1d, 0x1f, 0x21, 0xff}, nil},
+		{"#C5C8C6", color.RGBA{0xc5, 0xc8, 0xc6, 0xff}, nil},
+		{"#ffff80800000", color.RGBA{0xff, 0x80, 0x00, 0xff}, nil},
+		{"#ab", color.RGBA{}, errInvalidHex},
+		{"#abcd", color.RGBA{}, errInvalidHex},
+		{"#abcde", color.RGBA{}, errInvalidHex},
+		{"#ggg", color.RGBA{}, errInvalidHex},
+		{"#1d1f21ff", color.RGBA{}, errAlphaHex},
+		{"1d1f21", color.RGBA{}, errInvalidHex},
+	}
+
+	for _, tt := range tests {
+		got, err := hexToRGB(tt.hex)
+		if err != tt.wantErr {
+			t.Errorf("hexToRGB(%q) error = %v, want %v", tt.hex, err, tt.wantErr)
+			continue
+		}
+
+		if err == nil && got != tt.want {
+			t.Errorf("hexToRGB(%q) = %v, want %v", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestShorthandTheme(t *testing.T) {
+	p := readTestPalette(t, "shorthand.Xresources")
+
+	want := map[string]color.RGBA{
+		"foreground":  {0xff, 0xff, 0xff, 0xff},
+		"background":  {0, 0, 0, 0xff},
+		"cursorColor": {0xff, 0x88, 0, 0xff},
+		"color1":      {0xcc, 0, 0, 0xff},
+		"color9":      {0xff, 0x55, 0x55, 0xff},
+		"color15":     {0xff, 0xff, 0xff, 0xff},
+	}
+
+	for key, c := range want {
+		if p.keys[key] != c {
+			t.Errorf("%s = %v, want %v", key, p.keys[key], c)
+		}
+	}
+}
+
+func TestInvalidHexNamesKey(t *testing.T) {
+	_, err := convert(readTestValues(t, "invalid-shorthand.Xresources"))
+	if err == nil {
+		t.Fatal("convert() succeeded, want an error for the #abcd background")
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "invalid hex color") || !strings.Contains(msg, `key "background"`) || !strings.Contains(msg, "invalid-shorthand.Xresources:3") {
+		t.Errorf("convert() = %q, want an invalid hex color error naming the key and its line", msg)
+	}
+}
+
+func TestNearestColor(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	red := color.RGBA{0xcc, 0x66, 0x66, 0xff}
+
+	tests := []struct {
+		c          color.RGBA
+		candidates []color.RGBA
+		want       int
+	}{
+		{red, []color.RGBA{black, white, red}, 2},
+		{color.RGBA{0x10, 0x10, 0x10, 0xff}, []color.RGBA{white, black}, 1},
+		// the same color twice, the first one wins
+		{red, []color.RGBA{black, red, white, red}, 1},
+		// a dark gray halfway between black and a mid gray goes to the first
+		{color.RGBA{0x40, 0x40, 0x40, 0xff}, []color.RGBA{white, black, {0x80, 0x80, 0x80, 0xff}}, 1},
+		{color.RGBA{0x40, 0x40, 0x40, 0xff}, []color.RGBA{white, {0x80, 0x80, 0x80, 0xff}, black}, 1},
+		// a tie in distance from different directions
+		{color.RGBA{0x80, 0x80, 0x80, 0xff}, []color.RGBA{{0x90, 0x80, 0x80, 0xff}, {0x80, 0x70, 0x80, 0xff}}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := nearestColor(tt.c, tt.candidates); got != tt.want {
+			t.Errorf("nearestColor(%v, %v) = %d, want %d", tt.c, tt.candidates, got, tt.want)
+		}
+	}
+}
+
+func TestBgrDword(t *testing.T) {
+	tests := []struct {
+		c    color.RGBA
+		want string
+	}{
+		{color.RGBA{0, 0, 0, 0xff}, "00000000"},
+		{color.RGBA{0xff, 0, 0, 0xff}, "000000ff"},
+		{color.RGBA{0, 0xff, 0, 0xff}, "0000ff00"},
+		{color.RGBA{0, 0, 0xff, 0xff}, "00ff0000"},
+		{color.RGBA{0x1d, 0x1f, 0x21, 0xff}, "00211f1d"},
+		{color.RGBA{0x0a, 0x0b, 0x0c, 0}, "000c0b0a"},
+	}
+
+	for _, tt := range tests {
+		if got := bgrDword(tt.c); got != tt.want {
+			t.Errorf("bgrDword(%v) = %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// command is a subcommand, as in "urxvt-kitty validate theme.Xresources".
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands returns the subcommands, listed by --help in this order.
+func commands() []command {
+	return []command{
+		{"convert", "[flags] <file>... [<session>]", "convert a theme into a session or another terminal's format", runConvert},
+		{"preview", "[flags] <file>...", "print a preview of a theme, or recolor the current terminal with it", runPreview},
+		{"list", "[flags] <file>...", "print the colors read from a theme as a table, marking the missing ones", runList},
+		{"validate", "[flags] <file>...", "check that each theme can be read and converted", runValidate},
+		{"formats", "[flags]", "list the supported input and output formats", runFormats},
+		{"fetch", "[flags] <url>", "download a theme, to stdout or --output, keeping it in the download cache", runFetch},
+		{"version", "[--json]", "print the version, git commit, build date and Go version", runVersion},
+		{"completion", "bash|zsh|fish", "write the shell completion script for bash, zsh or fish", runCompletion},
+		{"config", "init [--force]", "write a config file with the defaults of every flag, all commented out", runConfig},
+	}
+}
+
+// run runs the command named by the first argument, after any of the flags
+// shared by all the commands. Anything else, such as the original
+// "urxvt-kitty file session" form, is passed on to convert as it is.
+func run(args []string) error {
+	root := newFlagSet("")
+
+	// convert parses the shared flags again, which mustn't count
+	// --verbose twice
+	before := verbose
+	toConvert := func() error {
+		verbose.set(before)
+		return runConvert(args)
+	}
+
+	err := root.Parse(args)
+	root.Visit(func(f *flag.Flag) { givenBefore[f.Name] = true })
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		printCommands(os.Stdout, root)
+		return err
+	case err != nil || root.NArg() == 0:
+		return toConvert()
+	}
+
+	name, rest := root.Arg(0), root.Args()[1:]
+	if name == "help" && len(rest) > 0 {
+		name, rest = rest[0], []string{"--help"}
+	} else if name == "help" {
+		printCommands(os.Stdout, root)
+		return nil
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd.run(rest)
+		}
+	}
+
+	return toConvert()
+}
+
+// newFlagSet returns the flag set of the named command, with the flags
+// shared by all of them. Their defaults are the current values, so shared
+// flags given before the command name are kept.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(strings.TrimSpace("urxvt-kitty "+name), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	fs.Var(&verbose, "verbose", "print details about how the input was read and the output written, give it twice for a trace of every line skipped")
+	fs.Var(&verbose, "v", "alias of --verbose")
+	fs.Var(verbosityFlag{&verbose, 2}, "vv", "same as --verbose given twice")
+	fs.BoolVar(&quiet, "quiet", quiet, "print nothing to stderr, not even errors, leaving the exit status to tell what failed")
+	fs.BoolVar(&quiet, "q", quiet, "alias of --quiet")
+	fs.StringVar(&configPath, "config", configPath, "config `file` to read the flag defaults from, instead of urxvt-kitty/config.toml in the user config directory")
+	fs.BoolVar(&noConfig, "no-config", noConfig, "don't read the flag defaults from a config file")
+	fs.BoolVar(&fetchOpts.insecure, "insecure", fetchOpts.insecure, "don't verify TLS certificates when downloading the input from a URL")
+	fs.BoolVar(&fetchOpts.noCache, "no-cache", fetchOpts.noCache, "don't read or write the download cache")
+	fs.BoolVar(&fetchOpts.refresh, "refresh", fetchOpts.refresh, "check with the server whether cached github: downloads are still current, other URLs are always checked")
+	fs.DurationVar(&fetchOpts.timeout, "timeout", fetchOpts.timeout, "time limit for each download attempt")
+	fs.IntVar(&fetchOpts.retries, "retries", fetchOpts.retries, "number of times to retry downloads failing with a server or connection error")
+
+	return fs
+}
+
+// sharedFlags are the names of the flags defined by newFlagSet.
+var sharedFlags = []string{"verbose", "vv", "quiet", "config", "no-config", "insecure", "no-cache", "refresh", "timeout", "retries"}
+
+// parseFlags parses the flags of a command wherever they appear among its
+// arguments, and returns the other arguments. With -h or --help, it prints
+// the help of the command and returns flag.ErrHelp, which isn't reported
+// as an error.
+func parseFlags(fs *flag.FlagSet, args []string, help func(io.Writer)) ([]string, error) {
+	if collectFlags != nil {
+		collectFlags(fs)
+		return nil, errFlagsOnly
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		help(os.Stdout)
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, withCode(exitUsage, flagError(fs, err))
+	}
+
+	if err := applyConfig(fs); err != nil {
+		return nil, err
+	}
+
+	return positional, nil
+}
+
+// inputFlags are the flags picking the theme to read, shared by the
+// commands reading one.
+type inputFlags struct {
+	fnames    stringList
+	from      *string
+	scheme    *string
+	profile   *string
+	theme     *string
+	member    *string
+	noInclude *bool
+}
+
+func addInputFlags(fs *flag.FlagSet) *inputFlags {
+	in := &inputFlags{}
+	fs.Var(&in.fnames, "input", "input `file` to read, can be repeated with later files overriding earlier ones")
+	in.from = fs.String("from", "", "input format, one of: "+strings.Join(inputFormatNames(), ", ")+" (detected when omitted)")
+	in.scheme = fs.String("scheme", "", "name of the scheme or session to read from input files holding several, like a Windows Terminal settings file or a .reg export")
+	in.profile = fs.String("profile", "", "id or name of the profile to read from a GNOME Terminal or Tilix dconf dump, or a Terminator config")
+	in.theme = fs.String("theme", "", "built-in theme to convert instead of an input file, see --list-themes")
+	in.member = fs.String("member", "", "path of the theme to convert inside the input archive")
+	in.noInclude = fs.Bool("no-include", false, "don't follow #include directives in the input file")
+	return in
+}
+
+func (in *inputFlags) decodeOptions() decodeOptions {
+	return decodeOptions{includes: !*in.noInclude, scheme: *in.scheme, profile: *in.profile}
+}
+
+// files returns the input files given with --input, --theme or as the
+// arguments, or stdin when there are none and it's piped.
+func (in *inputFlags) files(args []string) ([]string, error) {
+	fnames := append(append([]string{}, in.fnames...), args...)
+
+	switch {
+	case *in.theme != "" && len(fnames) > 0:
+		return nil, errors.New("--theme can't be combined with input files")
+	case *in.theme != "":
+		return []string{builtinPrefix + *in.theme}, nil
+	case len(fnames) == 0 && stdinIsPiped():
+		return []string{"-"}, nil
+	case len(fnames) == 0:
+		return nil, errors.New("no input file given, pass one as an argument or with --input, or pipe it to stdin")
+	}
+
+	return fnames, nil
+}
+
+// readValues decodes the input files, or the --member of each of them,
+// with later files overriding earlier ones. It also returns the theme name
+// stored in the last file that has one.
+func (in *inputFlags) readValues(fnames []string) (map[string]resource, string, error) {
+	opts := in.decodeOptions()
+	values := map[string]resource{}
+	themeName := ""
+
+	for _, fname := range fnames {
+		if isArchive(fname) && *in.member == "" {
+			return nil, "", fmt.Errorf("%s is an archive, pick the theme to convert with --member or convert them all with --all", sourceName(fname))
+		}
+
+		var (
+			decoded map[string]resource
+			name    string
+			err     error
+		)
+
+		if *in.member != "" {
+			decoded, name, err = decodeMember(fname, *in.member, *in.from, opts)
+		} else {
+			decoded, name, err = decodeInput(fname, *in.from, opts)
+		}
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		mergeResources(values, decoded)
+
+		if name != "" {
+			themeName = name
+		}
+	}
+
+	return values, themeName, nil
+}
+
+// readPalette decodes and converts the input files, returning the name of
+// the theme, or of the files when they don't store one.
+func (in *inputFlags) readPalette(fnames []string) (palette, string, error) {
+	values, name, err := in.readValues(fnames)
+	if err != nil {
+		return palette{}, "", err
+	}
+
+	if len(values) == 0 {
+		return palette{}, "", withCode(exitParse, fmt.Errorf("%s format is invalid: no color codes found", sourcesName(fnames)))
+	}
+
+	p, err := convert(values)
+	if err != nil {
+		return palette{}, "", err
+	}
+
+	switch {
+	case name != "":
+	case *in.theme != "":
+		name = *in.theme
+	default:
+		name = sourcesName(fnames)
+	}
+
+	return p, name, nil
+}
+
+func runPreview(args []string) error {
+	fs := newFlagSet("preview")
+	in := addInputFlags(fs)
+	apply := fs.Bool("apply", false, "also recolor the current terminal with the theme, writing to its tty")
+	previewSeconds := fs.Int("preview-seconds", 0, "with --apply, reset the terminal colors after this many seconds, or on Ctrl-C")
+	addFlagAliases(fs)
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "preview", fs) })
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *previewSeconds < 0:
+		return errors.New("--preview-seconds can't be negative")
+	case *previewSeconds > 0 && !*apply:
+		return errors.New("--preview-seconds only applies to --apply")
+	}
+
+	fnames, err := in.files(args)
+	if err != nil {
+		return err
+	}
+
+	p, name, err := in.readPalette(fnames)
+	if err != nil {
+		return err
+	}
+
+	writePreview(os.Stdout, name, p, isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "")
+
+	if *apply {
+		return applyPalette(fetchOpts.ctx, p, time.Duration(*previewSeconds)*time.Second)
+	}
+
+	return nil
+}
+
+// runValidate reads and converts each input file on its own, reporting
+// the ones that fail, so a whole directory of themes can be checked.
+func runValidate(args []string) error {
+	fs := newFlagSet("validate")
+	in := addInputFlags(fs)
+	addFlagAliases(fs)
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "validate", fs) })
+	if err != nil {
+		return err
+	}
+
+	fnames, err := in.files(args)
+	if err != nil {
+		return err
+	}
+
+	invalid, status := 0, 0
+	for _, fname := range fnames {
+		if _, _, err := in.readPalette([]string{fname}); err != nil {
+			fmt.Fprintf(os.Stdout, "%s: %s\n", sourceName(fname), err.Error())
+			invalid++
+
+			if status == 0 {
+				status = exitStatus(err)
+			}
+
+			continue
+		}
+
+		fmt.Fprintf(os.Stdout, "%s: ok\n", sourceName(fname))
+	}
+
+	if invalid > 0 {
+		// exit like the first invalid theme would on its own
+		return withCode(status, fmt.Errorf("%d of %d themes are invalid", invalid, len(fnames)))
+	}
+
+	return nil
+}
+
+func runFormats(args []string) error {
+	fs := newFlagSet("formats")
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "formats", fs) })
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		return fmt.Errorf("formats takes no arguments, got %q", args[0])
+	}
+
+	fmt.Fprintln(os.Stdout, "Input formats, for --from:")
+	for _, f := range inputFormats {
+		fmt.Fprintf(os.Stdout, "  %s\n", f.name)
+	}
+
+	fmt.Fprintln(os.Stdout, "\nOutput formats, for --to:")
+	for _, f := range outputFormats {
+		if f.ext != "" {
+			fmt.Fprintf(os.Stdout, "  %-18s %s\n", f.name, f.ext)
+		} else {
+			fmt.Fprintf(os.Stdout, "  %s\n", f.name)
+		}
+	}
+
+	return nil
+}
+
+// runFetch downloads a theme without converting it, which also stores it
+// in the download cache for later conversions.
+func runFetch(args []string) error {
+	fs := newFlagSet("fetch")
+	outFile := fs.String("output", "", "file to save the download to, instead of stdout")
+	force := fs.Bool("force", false, "replace the --output file when it already exists")
+	addFlagAliases(fs)
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "fetch", fs) })
+	if err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return errors.New("fetch downloads a single http(s) URL or github:owner/repo/path@ref")
+	}
+
+	rawURL, policy := args[0], cacheRevalidate
+	if expanded, ok := expandGitHub(rawURL); ok {
+		debugf("expanded %s to %s", rawURL, expanded)
+		rawURL, policy = expanded, cacheReuse
+	}
+
+	if !isURL(rawURL) {
+		return fmt.Errorf("%q isn't an http(s) URL or github:owner/repo/path@ref", args[0])
+	}
+
+	body, err := fetchURL(rawURL, policy)
+	if err != nil {
+		return withCode(exitInput, err)
+	}
+
+	if *outFile == "" {
+		if _, err := os.Stdout.Write(body); err != nil {
+			return withCode(exitOutput, fmt.Errorf("can't write to stdout: %s", err.Error()))
+		}
+
+		return nil
+	}
+
+	return writeOutputFile(*outFile, body, *force, false)
+}
+
+func runVersion(args []string) error {
+	fs := newFlagSet("version")
+	asJSON := fs.Bool("json", false, "print the version as JSON")
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "version", fs) })
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		return fmt.Errorf("version takes no arguments, got %q", args[0])
+	}
+
+	return writeVersion(os.Stdout, *asJSON)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withFlags keeps the test from reading the user's config file, and puts
+// back the globals the shared flags set once it's over.
+func withFlags(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	prevVerbose, prevQuiet, prevFetch := verbose, quiet, fetchOpts
+	prevConfig, prevNoConfig, prevGiven := configPath, noConfig, givenBefore
+	t.Cleanup(func() {
+		verbose, quiet, fetchOpts = prevVerbose, prevQuiet, prevFetch
+		configPath, noConfig, givenBefore = prevConfig, prevNoConfig, prevGiven
+	})
+
+	configPath, noConfig, givenBefore = "", true, map[string]bool{}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+		to   string
+		all  bool
+	}{
+		{[]string{"themes.zip", "--all"}, []string{"themes.zip"}, "kitty", true},
+		{[]string{"--all", "themes.zip"}, []string{"themes.zip"}, "kitty", true},
+		{[]string{"nord.Xresources", "--to", "css", "Nord"}, []string{"nord.Xresources", "Nord"}, "css", false},
+		{[]string{"nord.Xresources", "--to=css", "Nord", "-all"}, []string{"nord.Xresources", "Nord"}, "css", true},
+		{[]string{"nord.Xresources", "--", "--all"}, []string{"nord.Xresources", "--all"}, "kitty", false},
+		{[]string{"--to", "css", "--", "-", "--to"}, []string{"-", "--to"}, "css", false},
+		{[]string{"-", "Nord"}, []string{"-", "Nord"}, "kitty", false},
+		{nil, nil, "kitty", false},
+	}
+
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		to := fs.String("to", "kitty", "")
+		all := fs.Bool("all", false, "")
+
+		got, err := parseInterspersed(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseInterspersed(%q) = %s", tt.args, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt.want) || *to != tt.to || *all != tt.all {
+			t.Errorf("parseInterspersed(%q) = %q with --to %s and --all %t, want %q with --to %s and --all %t", tt.args, got, *to, *all, tt.want, tt.to, tt.all)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	withFlags(t)
+
+	fs := newFlagSet("convert")
+	output := fs.String("output", "", "")
+	session := fs.String("session", "", "")
+	addFlagAliases(fs)
+
+	args := []string{"theme.Xresources", "-o", "out.reg", "-q", "-v", "-s", "Work", "-v", "--", "-s"}
+	got, err := parseFlags(fs, args, func(io.Writer) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"theme.Xresources", "-s"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFlags() = %q, want %q", got, want)
+	}
+
+	if *output != "out.reg" || *session != "Work" || !quiet || verbose != 2 {
+		t.Errorf("-o %q, -s %q, -q %t and -v twice %d, want out.reg, Work, true and 2", *output, *session, quiet, verbose)
+	}
+
+	// an alias the set has no flag for isn't defined
+	if fs.Lookup("i") != nil {
+		t.Error("-i is defined without --input")
+	}
+}
+
+func TestParseFlagsErrors(t *testing.T) {
+	withFlags(t)
+
+	fs := newFlagSet("convert")
+	fs.String("output", "", "")
+	addFlagAliases(fs)
+
+	if _, err := parseFlags(fs, []string{"theme.Xresources", "--ouptut", "x"}, func(io.Writer) {}); exitStatus(err) != exitUsage || err.Error() != "unknown flag --ouptut, did you mean --output?" {
+		t.Errorf("parseFlags() = %v with exit status %d, want a usage error suggesting --output", err, exitStatus(err))
+	}
+
+	helped := false
+	if _, err := parseFlags(fs, []string{"theme.Xresources", "-h"}, func(io.Writer) { helped = true }); !errors.Is(err, flag.ErrHelp) || !helped {
+		t.Errorf("parseFlags() = %v, printing the help %t, want flag.ErrHelp after printing it", err, helped)
+	}
+}
+
+func TestRunDispatch(t *testing.T) {
+	theme := filepath.Join("testdata", "mixed-case.Xresources")
+
+	tests := []struct {
+		name  string
+		args  []string
+		want  string
+		quiet bool
+	}{
+		{"convert command", []string{"convert", theme, "Work", "--to", "xresources"}, "! Work\n", false},
+		{"implicit convert", []string{theme, "Work", "--to", "xresources"}, "! Work\n", false},
+		{"shared flag before the command", []string{"-q", "convert", "--to=termux", theme, "Work"}, "foreground=#c5c8c6\n", true},
+		{"shared flag after the arguments", []string{"convert", theme, "Work", "--to", "termux", "--quiet"}, "foreground=#c5c8c6\n", true},
+		{"command name as the session", []string{"--to", "xresources", theme, "convert"}, "! convert\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFlags(t)
+
+			out := filepath.Join(t.TempDir(), "out")
+			if err := run(append(tt.args, "--output", out)); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(string(got), tt.want) {
+				t.Errorf("output starts with %q, want %q", firstLine(string(got)), tt.want)
+			}
+
+			if quiet != tt.quiet {
+				t.Errorf("--quiet is %t, want %t", quiet, tt.quiet)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// errFlagsOnly is returned by parseFlags while collecting the flags of a
+// command, see commandFlags.
+var errFlagsOnly = errors.New("only collecting the flags of the command")
+
+// collectFlags, when set, gets the flag set of the command being run,
+// which then returns before parsing anything.
+var collectFlags func(fs *flag.FlagSet)
+
+// commandFlags returns the flag set of the command, by running it with
+// collectFlags set, so the completion scripts are generated from the very
+// flags the command parses.
+func commandFlags(cmd command) *flag.FlagSet {
+	var fs *flag.FlagSet
+
+	collectFlags = func(f *flag.FlagSet) { fs = f }
+	defer func() { collectFlags = nil }()
+
+	cmd.run(nil)
+	return fs
+}
+
+// flagValues are the values offered when completing the flags that take
+// one of a fixed list.
+var flagValues = map[string]func() []string{
+	"from":          inputFormatNames,
+	"to":            outputFormatNames,
+	"theme":         builtinThemeNames,
+	"registry-root": func() []string { return sortedKeys(registryRoots) },
+	"encoding":      func() []string { return []string{"utf8", "utf16"} },
+}
+
+// fileFlags take a file name, and dirFlags a directory name.
+var (
+	fileFlags = []string{"input", "output", "combined", "config", "template", "merge", "sessions-file"}
+	dirFlags  = []string{"out-dir"}
+)
+
+// completionFlag is a flag as the completion scripts describe it.
+type completionFlag struct {
+	name       string
+	alias      string
+	usage      string
+	takesValue bool
+	repeatable bool
+	values     []string
+	file       bool
+	dir        bool
+}
+
+// completionFlags returns the flags of the command, without the aliases,
+// which are set on the flag they stand for.
+func completionFlags(cmd command) []completionFlag {
+	fs := commandFlags(cmd)
+
+	aliases := map[string]bool{}
+	for _, alias := range flagAliases {
+		aliases[alias] = true
+	}
+
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		if aliases[f.Name] {
+			return
+		}
+
+		cf := completionFlag{name: f.Name, usage: flagSummary(f.Usage), file: contains(fileFlags, f.Name), dir: contains(dirFlags, f.Name)}
+		if alias := flagAliases[f.Name]; alias != "" && fs.Lookup(alias) != nil {
+			cf.alias = alias
+		}
+
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !bf.IsBoolFlag() {
+			cf.takesValue = true
+		}
+
+		if _, ok := f.Value.(*stringList); ok {
+			cf.repeatable = true
+		}
+
+		if values := flagValues[f.Name]; values != nil {
+			cf.values = values()
+		}
+
+		flags = append(flags, cf)
+	})
+
+	return flags
+}
+
+// flagSummary shortens a flag usage for the descriptions shown by the
+// shells, dropping the list of values they complete anyway, any remark
+// in parentheses, and the backquotes naming the value.
+func flagSummary(usage string) string {
+	usage = strings.ReplaceAll(usage, "`", "")
+
+	for _, marker := range []string{", one of: ", " ("} {
+		if pos := strings.Index(usage, marker); pos > 0 {
+			usage = usage[:pos]
+		}
+	}
+
+	return usage
+}
+
+func commandNames() []string {
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+
+	return append(names, "help")
+}
+
+func runCompletion(args []string) error {
+	fs := newFlagSet("completion")
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "completion", fs) })
+	if err != nil {
+		return err
+	}
+
+	shell := ""
+	if len(args) == 1 {
+		shell = args[0]
+	}
+
+	switch shell {
+	case "bash":
+		return writeBashCompletion(os.Stdout)
+	case "zsh":
+		return writeZshCompletion(os.Stdout)
+	case "fish":
+		return writeFishCompletion(os.Stdout)
+	}
+
+	return errors.New("pick the shell to write the completion script for, one of: bash, fish, zsh")
+}
+
+// writeBashCompletion writes a completion script for bash, to be sourced
+// from ~/.bashrc or saved in the bash-completion directory.
+func writeBashCompletion(w io.Writer) error {
+	var valueCases, fileCases, dirCases, otherCases, flagCases []string
+	seen := map[string]bool{}
+
+	for _, cmd := range commands() {
+		var words []string
+
+		for _, f := range completionFlags(cmd) {
+			words = append(words, "--"+f.name)
+			if f.alias != "" {
+				words = append(words, "-"+f.alias)
+			}
+
+			if seen[f.name] || !f.takesValue {
+				continue
+			}
+
+			seen[f.name] = true
+
+			names := "--" + f.name
+			if f.alias != "" {
+				names += "|-" + f.alias
+			}
+
+			switch {
+			case f.values != nil:
+				valueCases = append(valueCases, fmt.Sprintf("        %s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;", names, strings.Join(f.values, " ")))
+			case f.dir:
+				dirCases = append(dirCases, names)
+			case f.file:
+				fileCases = append(fileCases, names)
+			default:
+				otherCases = append(otherCases, names)
+			}
+		}
+
+		flagCases = append(flagCases, fmt.Sprintf("            %s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;", cmd.name, strings.Join(words, " ")))
+	}
+
+	names := strings.Join(commandNames(), " ")
+
+	fmt.Fprintln(w, `# bash completion for urxvt-kitty, written by "urxvt-kitty completion bash"`)
+	fmt.Fprintln(w, `_urxvt_kitty() {`)
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" cmd=convert i`)
+	fmt.Fprintln(w, `    for ((i = 1; i < COMP_CWORD; i++)); do`)
+	fmt.Fprintln(w, `        case "${COMP_WORDS[i]}" in`)
+	fmt.Fprintf(w, "            %s) cmd=\"${COMP_WORDS[i]}\"; break ;;\n", strings.ReplaceAll(names, " ", "|"))
+	fmt.Fprintln(w, `            -*) ;;`)
+	fmt.Fprintln(w, `            *) break ;;`)
+	fmt.Fprintln(w, `        esac`)
+	fmt.Fprintln(w, `    done`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `    case "$prev" in`)
+	for _, c := range valueCases {
+		fmt.Fprintln(w, c)
+	}
+	fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n", strings.Join(dirCases, "|"))
+	fmt.Fprintf(w, "        %s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(fileCases, "|"))
+	fmt.Fprintf(w, "        %s) COMPREPLY=(); return ;;\n", strings.Join(otherCases, "|"))
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `    if [[ "$cur" == -* ]]; then`)
+	fmt.Fprintln(w, `        case "$cmd" in`)
+	for _, c := range flagCases {
+		fmt.Fprintln(w, c)
+	}
+	fmt.Fprintln(w, `        esac`)
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `    if [[ "$cmd" == help || "$cmd" == completion ]]; then`)
+	fmt.Fprintf(w, "        [[ \"$cmd\" == help ]] && COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", names)
+	fmt.Fprintln(w, `        [[ "$cmd" == completion ]] && COMPREPLY=($(compgen -W "bash fish zsh" -- "$cur"))`)
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `    if ((COMP_CWORD == 1)); then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", names)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, `    COMPREPLY+=($(compgen -f -- "$cur"))`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `complete -o filenames -F _urxvt_kitty urxvt-kitty`)
+
+	return nil
+}
+
+// writeZshCompletion writes a completion script for zsh, to be saved as
+// _urxvt-kitty in a directory of $fpath.
+func writeZshCompletion(w io.Writer) error {
+	fmt.Fprintln(w, `#compdef urxvt-kitty`)
+	fmt.Fprintln(w, `# zsh completion for urxvt-kitty, written by "urxvt-kitty completion zsh"`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `_urxvt_kitty() {`)
+	fmt.Fprintln(w, `  local cmd=convert i`)
+	fmt.Fprintln(w, `  for ((i = 2; i < CURRENT; i++)); do`)
+	fmt.Fprintln(w, `    case $words[i] in`)
+	fmt.Fprintf(w, "      (%s) cmd=$words[i]; break ;;\n", strings.Join(commandNames(), "|"))
+	fmt.Fprintln(w, `      (-*) ;;`)
+	fmt.Fprintln(w, `      (*) break ;;`)
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `  done`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `  if ((CURRENT == 2)) && [[ $words[2] != -* ]]; then`)
+	fmt.Fprintln(w, `    local -a commands=(`)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "      %s\n", zshQuote(cmd.name+":"+cmd.summary))
+	}
+	fmt.Fprintf(w, "      %s\n", zshQuote("help:show the help of a command"))
+	fmt.Fprintln(w, `    )`)
+	fmt.Fprintln(w, `    _alternative 'commands:command:_describe command commands' 'files:file:_files'`)
+	fmt.Fprintln(w, `    return`)
+	fmt.Fprintln(w, `  fi`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `  case $cmd in`)
+	fmt.Fprintf(w, "    (help) _values command %s ;;\n", strings.Join(commandNames(), " "))
+
+	for _, cmd := range commands() {
+		var specs []string
+		for _, f := range completionFlags(cmd) {
+			specs = append(specs, zshFlagSpec(f))
+		}
+
+		switch cmd.name {
+		case "completion":
+			specs = append(specs, `'1:shell:(bash fish zsh)'`)
+		case "formats", "version":
+		default:
+			specs = append(specs, `'*:file:_files'`)
+		}
+
+		fmt.Fprintf(w, "    (%s)\n      _arguments -s \\\n        %s\n      ;;\n", cmd.name, strings.Join(specs, " \\\n        "))
+	}
+
+	fmt.Fprintln(w, `  esac`)
+	fmt.Fprintln(w, `}`)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `compdef _urxvt_kitty urxvt-kitty`)
+
+	return nil
+}
+
+// zshFlagSpec returns the _arguments spec of the flag, along with its
+// alias when it has one.
+func zshFlagSpec(f completionFlag) string {
+	desc := "[" + strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(f.usage) + "]"
+
+	arg := ""
+	switch {
+	case !f.takesValue:
+	case f.values != nil:
+		arg = ":" + f.name + ":(" + strings.Join(f.values, " ") + ")"
+	case f.dir:
+		arg = ":directory:_files -/"
+	case f.file:
+		arg = ":file:_files"
+	default:
+		arg = ":" + f.name + ": "
+	}
+
+	repeat, eq := "", ""
+	if f.repeatable {
+		repeat = "*"
+	}
+
+	if f.takesValue {
+		eq = "="
+	}
+
+	if f.alias == "" {
+		return zshQuote(repeat + "--" + f.name + eq + desc + arg)
+	}
+
+	exclude := ""
+	if !f.repeatable {
+		exclude = "(-" + f.alias + " --" + f.name + ")"
+	}
+
+	return zshQuote(exclude+repeat) + "{-" + f.alias + ",--" + f.name + eq + "}" + zshQuote(desc+arg)
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeFishCompletion writes a completion script for fish, to be saved in
+// ~/.config/fish/completions/urxvt-kitty.fish.
+func writeFishCompletion(w io.Writer) error {
+	names := strings.Join(commandNames(), " ")
+
+	fmt.Fprintln(w, `# fish completion for urxvt-kitty, written by "urxvt-kitty completion fish"`)
+	fmt.Fprintf(w, "set -l commands %s\n", names)
+	fmt.Fprintln(w, ``)
+	fmt.Fprintln(w, `complete -c urxvt-kitty -n "not __fish_seen_subcommand_from $commands" -F`)
+
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "complete -c urxvt-kitty -n \"not __fish_seen_subcommand_from $commands\" -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+
+	fmt.Fprintf(w, "complete -c urxvt-kitty -n \"__fish_seen_subcommand_from help\" -x -a %s\n", fishQuote(names))
+	fmt.Fprintln(w, `complete -c urxvt-kitty -n "__fish_seen_subcommand_from completion" -x -a 'bash fish zsh'`)
+
+	for _, cmd := range commands() {
+		cond := fmt.Sprintf("__fish_seen_subcommand_from %s", cmd.name)
+		if cmd.name == "convert" {
+			cond += "; or not __fish_seen_subcommand_from $commands"
+		}
+
+		fmt.Fprintln(w, ``)
+
+		for _, f := range completionFlags(cmd) {
+			line := fmt.Sprintf("complete -c urxvt-kitty -n %q -l %s", cond, f.name)
+			if f.alias != "" {
+				line += " -s " + f.alias
+			}
+
+			switch {
+			case !f.takesValue:
+			case f.values != nil:
+				line += " -x -a " + fishQuote(strings.Join(f.values, " "))
+			case f.dir:
+				line += " -x -a '(__fish_complete_directories)'"
+			case f.file:
+				line += " -r -F"
+			default:
+				line += " -x"
+			}
+
+			fmt.Fprintln(w, line+" -d "+fishQuote(f.usage))
+		}
+	}
+
+	return nil
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
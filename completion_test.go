@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeScript writes the completion script for the shell to a temporary
+// file, skipping the test when the shell isn't installed.
+func writeScript(t *testing.T, shell string, write func(w *bytes.Buffer) error) string {
+	t.Helper()
+
+	if _, err := exec.LookPath(shell); err != nil {
+		t.Skipf("%s isn't installed", shell)
+	}
+
+	var b bytes.Buffer
+	if err := write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "urxvt-kitty."+shell)
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestCompletionSyntax(t *testing.T) {
+	for shell, write := range map[string]func(w *bytes.Buffer) error{
+		"bash": func(w *bytes.Buffer) error { return writeBashCompletion(w) },
+		"zsh":  func(w *bytes.Buffer) error { return writeZshCompletion(w) },
+		"fish": func(w *bytes.Buffer) error { return writeFishCompletion(w) },
+	} {
+		t.Run(shell, func(t *testing.T) {
+			path := writeScript(t, shell, write)
+
+			if out, err := exec.Command(shell, "-n", path).CombinedOutput(); err != nil {
+				t.Errorf("%s -n failed: %s\n%s", shell, err, out)
+			}
+		})
+	}
+}
+
+// bashComplete returns what the bash completion offers for the words,
+// the last one being completed.
+func bashComplete(t *testing.T, path string, words ...string) []string {
+	t.Helper()
+
+	script := `source "$1"; shift; COMP_WORDS=("$@"); COMP_CWORD=$(($# - 1)); _urxvt_kitty; printf '%s\n' "${COMPREPLY[@]}"`
+	cmd := exec.Command("bash", append([]string{"-c", script, "bash", path, "urxvt-kitty"}, words...)...)
+	cmd.Dir = t.TempDir()
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("completing %q failed: %s", words, err)
+	}
+
+	return strings.Fields(string(out))
+}
+
+func TestBashCompletion(t *testing.T) {
+	path := writeScript(t, "bash", func(w *bytes.Buffer) error { return writeBashCompletion(w) })
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{"convert", "--to", ""}, outputFormatNames()},
+		{[]string{"--to", ""}, outputFormatNames()},
+		{[]string{"convert", "--to", "kitty"}, []string{"kitty", "kitty-conf", "kitty-portable"}},
+		{[]string{"preview", "--from", ""}, inputFormatNames()},
+		{[]string{"--theme", ""}, builtinThemeNames()},
+		{[]string{"completion", ""}, []string{"bash", "fish", "zsh"}},
+		{[]string{"vers"}, []string{"version"}},
+		{[]string{"convert", "--ou"}, []string{"--out-dir", "--output"}},
+	}
+
+	for _, tt := range tests {
+		if got := bashComplete(t, path, tt.words...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("completing %q = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reConEmuValue = regexp.MustCompile(`<value\b[^>]*>`)
+	reXMLAttr     = regexp.MustCompile(`([A-Za-z]+)="([^"]*)"`)
+)
+
+// consoleColorOrder maps the entries of the Windows console color table,
+// also used by ConEmu, to the ANSI palette entries they hold. The console
+// puts blue where ANSI puts red, and cyan where ANSI puts yellow, so
+// mapping an index twice gives it back.
+var consoleColorOrder = []int{0, 4, 2, 6, 1, 5, 3, 7, 8, 12, 10, 14, 9, 13, 11, 15}
+
+// consoleColorTable returns the palette in the Windows console order.
+func consoleColorTable(p palette) []color.RGBA {
+	entries := make([]color.RGBA, 0, 16)
+	for _, n := range consoleColorOrder {
+		entries = append(entries, p.keys[fmt.Sprintf("color%d", n)])
+	}
+
+	return entries
+}
+
+func detectConEmu(content string) bool {
+	return strings.Contains(content, `"ColorTable00"`)
+}
+
+// xmlAttrs returns the attributes of a single XML tag.
+func xmlAttrs(tag string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range reXMLAttr.FindAllStringSubmatch(tag, -1) {
+		attrs[m[1]] = m[2]
+	}
+
+	return attrs
+}
+
+// conEmuColor converts a ConEmu color table dword, written in hex as
+// 0x00BBGGRR, to the "#rrggbb" notation.
+func conEmuColor(data string) (string, error) {
+	if len(data) != 8 {
+		return "", fmt.Errorf("expected 8 hex digits, got %q", data)
+	}
+
+	c, err := parseColor("#" + data[6:8] + data[4:6] + data[2:4])
+	if err != nil {
+		return "", err
+	}
+
+	return hexColor(c), nil
+}
+
+// parseConEmu reads the ColorTable00 through ColorTable15 values of a
+// ConEmu XML palette, which are in the Windows console order. The
+// foreground and background are the palette entries picked by
+// TextColorIdx and BackColorIdx, which default to 7 and 0 when unset or
+// set to automatic, and since ConEmu has no cursor color the foreground is
+// used instead.
+func parseConEmu(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+	indexes := map[string]int{"TextColorIdx": 7, "BackColorIdx": 0}
+
+	for _, line := range lines {
+		for _, tag := range reConEmuValue.FindAllString(line.text, -1) {
+			attrs := xmlAttrs(tag)
+
+			name, data := attrs["name"], attrs["data"]
+
+			if _, found := indexes[name]; found {
+				n, err := strconv.ParseUint(data, 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("%s: invalid palette index for %s: %q", line, name, data)
+				}
+
+				if n < 16 {
+					indexes[name] = int(n)
+				}
+
+				continue
+			}
+
+			if !strings.HasPrefix(name, "ColorTable") {
+				continue
+			}
+
+			n, err := strconv.Atoi(strings.TrimPrefix(name, "ColorTable"))
+			if err != nil || n > 15 {
+				continue
+			}
+
+			value, err := conEmuColor(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid color for %s: %s", line, name, err.Error())
+			}
+
+			debugf("%s: using %s as color%d: %s", line, name, consoleColorOrder[n], value)
+			values[fmt.Sprintf("color%d", consoleColorOrder[n])] = resource{value: value, source: line}
+		}
+	}
+
+	for name, key := range map[string]string{"TextColorIdx": "foreground", "BackColorIdx": "background"} {
+		n := consoleColorOrder[indexes[name]]
+		if res, found := values[fmt.Sprintf("color%d", n)]; found {
+			debugf("%s: using color%d for %s", res.source, n, key)
+			values[key] = res
+		}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "ConEmu palette")
+	return values, nil
+}
+
+// conEmuName returns the name of the palette, if the export has one.
+func conEmuName(lines []sourceLine, _ decodeOptions) string {
+	for _, line := range lines {
+		for _, tag := range reConEmuValue.FindAllString(line.text, -1) {
+			attrs := xmlAttrs(tag)
+
+			if attrs["name"] == "Name" {
+				return html.UnescapeString(attrs["data"])
+			}
+		}
+	}
+
+	return ""
+}
+
+// writeConEmu writes the colors as a ConEmu palette, in the Windows
+// console order, to be pasted into the Colors key of ConEmu.xml. ConEmu
+// picks the foreground and background from the palette, so they're set to
+// the entries closest to the theme's.
+func writeConEmu(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	entries := consoleColorTable(p)
+
+	fmt.Fprintln(w, `<key name="Palette1">`)
+	fmt.Fprintf(w, "\t<value name=\"Name\" type=\"string\" data=\"%s\"/>\n", html.EscapeString(sname))
+	fmt.Fprintf(w, "\t<value name=\"TextColorIdx\" type=\"hex\" data=\"%02x\"/>\n", nearestColor(p.keys["foreground"], entries))
+	fmt.Fprintf(w, "\t<value name=\"BackColorIdx\" type=\"hex\" data=\"%02x\"/>\n", nearestColor(p.keys["background"], entries))
+
+	for i, c := range entries {
+		fmt.Fprintf(w, "\t<value name=\"ColorTable%02d\" type=\"dword\" data=\"%s\"/>\n", i, bgrDword(c))
+	}
+
+	fmt.Fprintln(w, "</key>")
+
+	return nil
+}
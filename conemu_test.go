@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteConEmu(t *testing.T) {
+	p := builtinTestPalette(t, "gruvbox-dark")
+
+	var b bytes.Buffer
+	if err := writeConEmu(&b, "Gruvbox <Dark>", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "gruvbox-dark.conemu.xml", b.Bytes())
+
+	// the palette reads back as the same colors, swapped back to the ANSI
+	// order
+	lines, err := scanLines("gruvbox-dark.conemu.xml", bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := parseConEmu(lines, decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := conEmuName(lines, decodeOptions{}); got != "Gruvbox <Dark>" {
+		t.Errorf("conEmuName() = %q, want the session name", got)
+	}
+
+	got, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range append(paletteKeys(), "foreground", "background") {
+		if got.keys[key] != p.keys[key] {
+			t.Errorf("%s read back as %v, want %v", key, got.keys[key], p.keys[key])
+		}
+	}
+}
+
+func TestConsoleColorOrder(t *testing.T) {
+	for i, n := range consoleColorOrder {
+		if consoleColorOrder[n] != i {
+			t.Errorf("console entry %d holds ANSI color %d, which maps back to %d", i, n, consoleColorOrder[n])
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configPath is the config file given with --config, and noConfig skips
+// reading any.
+var (
+	configPath string
+	noConfig   bool
+)
+
+// givenBefore are the shared flags given before the command name, which
+// the config file mustn't override either.
+var givenBefore = map[string]bool{}
+
+// configOnly are the flags that can't be set from the config file itself.
+var configOnly = []string{"config", "no-config"}
+
+// configSkipped are the flags left out of the file written by "config
+// init", as they pick what the command does rather than how.
+var configSkipped = []string{"version", "json", "list", "list-themes", "vv"}
+
+// configValue is a "name = value" line of the config file, where arrays
+// set flags that can be repeated once for each of their values.
+type configValue struct {
+	source  sourceLine
+	section string
+	name    string
+	values  []string
+}
+
+// defaultConfigPath returns where the config file is read from unless
+// --config picks another one: urxvt-kitty/config.toml in the user config
+// directory, $XDG_CONFIG_HOME or ~/.config on Linux and %AppData% on
+// Windows.
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "urxvt-kitty", "config.toml"), nil
+}
+
+// applyConfig sets the flags of the command from the config file, for the
+// flags not given on the command line. Keys at the top of the file apply
+// to every command that has the flag, and the ones in a [command] section
+// only to that command, overriding the former.
+func applyConfig(fs *flag.FlagSet) error {
+	if noConfig {
+		return nil
+	}
+
+	path := configPath
+	if path == "" {
+		var err error
+		if path, err = defaultConfigPath(); err != nil {
+			debugf("not reading a config file: %s", err.Error())
+			return nil
+		}
+
+		// the default config file is optional
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+
+	values, err := readConfig(path)
+	if err != nil {
+		return withCode(exitUsage, err)
+	}
+
+	debugf("reading the flag defaults from %s", path)
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[flagName(f.Name)] = true })
+	for name := range givenBefore {
+		given[flagName(name)] = true
+	}
+
+	command := strings.TrimSpace(strings.TrimPrefix(fs.Name(), "urxvt-kitty"))
+	if command == "" {
+		command = "convert"
+	}
+
+	picked := map[string]configValue{}
+	var order []string
+
+	for _, v := range values {
+		if fs.Lookup(v.name) == nil || given[v.name] || (v.section != "" && v.section != command) {
+			continue
+		}
+
+		if _, found := picked[v.name]; !found {
+			order = append(order, v.name)
+		}
+
+		// a section overrides the top of the file, whatever the order
+		if prev, found := picked[v.name]; !found || v.section != "" || prev.section == "" {
+			picked[v.name] = v
+		}
+	}
+
+	for _, name := range order {
+		v := picked[name]
+		for _, value := range v.values {
+			if err := fs.Set(name, value); err != nil {
+				return withCode(exitUsage, fmt.Errorf("%s: invalid value for %s: %s", v.source, name, err.Error()))
+			}
+		}
+	}
+
+	// only now that --verbose and --quiet are set from the file too
+	for _, name := range order {
+		debugf("%s: using %s = %s", picked[name].source, name, strings.Join(picked[name].values, ", "))
+	}
+
+	return nil
+}
+
+// flagName returns the full name of a flag given by its one letter alias.
+func flagName(name string) string {
+	for full, alias := range flagAliases {
+		if alias == name {
+			return full
+		}
+	}
+
+	if name == "vv" {
+		return "verbose"
+	}
+
+	return name
+}
+
+// readConfig reads the config file, TOML style "name = value" lines named
+// after the flags, optionally grouped in [command] sections. Unknown flags
+// and sections are reported along with the line they're on.
+func readConfig(path string) ([]configValue, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the config file: %s", err.Error())
+	}
+
+	known := map[string][]string{}
+	var all, sections []string
+	for _, cmd := range commands() {
+		sections = append(sections, cmd.name)
+
+		if fs := commandFlags(cmd); fs != nil {
+			fs.VisitAll(func(f *flag.Flag) {
+				if len(known[f.Name]) == 0 {
+					all = append(all, f.Name)
+				}
+
+				known[f.Name] = append(known[f.Name], cmd.name)
+			})
+		}
+	}
+
+	var (
+		values  []configValue
+		section string
+	)
+
+	for i, line := range strings.Split(string(content), "\n") {
+		src := sourceLine{file: path, num: i + 1}
+		text := strings.TrimSpace(line)
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if m := reINISection.FindStringSubmatch(text); m != nil {
+			section = m[2]
+			if !contains(sections, section) {
+				return nil, fmt.Errorf("%s: unknown section [%s], sections are named after the commands: %s", src, section, strings.Join(sections, ", "))
+			}
+
+			continue
+		}
+
+		m := reINIEntry.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("%s: expected a \"name = value\" line", src)
+		}
+
+		v := configValue{source: src, section: section, name: strings.Trim(m[1], `"`)}
+
+		switch {
+		case contains(configOnly, v.name):
+			return nil, fmt.Errorf("%s: %s can't be set in the config file", src, v.name)
+		case len(known[v.name]) == 0:
+			return nil, fmt.Errorf("%s: unknown flag %q%s", src, v.name, suggestFlag(v.name, all))
+		case len(v.name) == 1 || v.name == "vv":
+			return nil, fmt.Errorf("%s: %s is a short alias, use the full flag name %q", src, v.name, flagName(v.name))
+		case section != "" && !contains(known[v.name], section):
+			return nil, fmt.Errorf("%s: %s isn't a flag of the %s command", src, v.name, section)
+		}
+
+		if v.values, err = configValues(templateValueText(m[2])); err != nil {
+			return nil, fmt.Errorf("%s: invalid value for %s: %s", src, v.name, err.Error())
+		}
+
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// suggestFlag names the closest of the flags, when it's close enough to
+// be a typo.
+func suggestFlag(name string, names []string) string {
+	if best := closestName(name, names); editDistance(name, best) <= 2 {
+		return fmt.Sprintf(", did you mean %q?", best)
+	}
+
+	return ""
+}
+
+// configValues returns the values of a config line: a quoted string, an
+// array of them, or a bare boolean, number or duration.
+func configValues(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") {
+		s, err := configString(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{s}, nil
+	}
+
+	var values []string
+	rest := strings.TrimSpace(value[1:])
+
+	for !strings.HasPrefix(rest, "]") {
+		item := templateValueText(rest)
+		if !strings.HasPrefix(item, `"`) && !strings.HasPrefix(item, "'") {
+			return nil, errors.New("arrays hold quoted strings")
+		}
+
+		s, err := configString(item)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, s)
+
+		rest = strings.TrimSpace(rest[len(item):])
+		switch {
+		case strings.HasPrefix(rest, ","):
+			rest = strings.TrimSpace(rest[1:])
+		case !strings.HasPrefix(rest, "]"):
+			return nil, errors.New("expected \",\" or \"]\" after an array value")
+		}
+	}
+
+	if strings.TrimSpace(rest[1:]) != "" {
+		return nil, errors.New("unexpected text after the array")
+	}
+
+	return values, nil
+}
+
+func configString(value string) (string, error) {
+	switch {
+	case value == "":
+		return "", errors.New("missing value")
+	case strings.HasPrefix(value, `"`):
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", value)
+		}
+
+		return s, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("invalid string %s", value)
+		}
+
+		return value[1 : len(value)-1], nil
+	}
+
+	return value, nil
+}
+
+// runConfig runs "config init", writing a config file with every flag
+// commented out, set to its default.
+func runConfig(args []string) error {
+	fs := newFlagSet("config")
+	force := fs.Bool("force", false, "replace the config file when it already exists")
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "config", fs) })
+	if err != nil {
+		return err
+	}
+
+	if len(args) != 1 || args[0] != "init" {
+		return errors.New("config only has the init subcommand, which writes a commented config file")
+	}
+
+	path := configPath
+	if path == "" {
+		if path, err = defaultConfigPath(); err != nil {
+			return fmt.Errorf("can't find the config directory, pick the file with --config: %s", err.Error())
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return withCode(exitOutput, fmt.Errorf("can't create the config directory: %s", err.Error()))
+	}
+
+	var b strings.Builder
+	writeConfigSkeleton(&b)
+
+	if err := writeOutputFile(path, []byte(b.String()), *force, false); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "wrote %s\n", path)
+	return nil
+}
+
+// writeConfigSkeleton writes a config file setting none of the flags, with
+// each of them commented out along with its description, in the sections
+// of the --help output.
+func writeConfigSkeleton(w io.Writer) {
+	fs := commandFlags(commands()[0])
+
+	fmt.Fprintln(w, "# Defaults of the urxvt-kitty flags, named as on the command line without")
+	fmt.Fprintln(w, "# the leading dashes. Flags given on the command line override them, and")
+	fmt.Fprintln(w, "# flags a command doesn't have are ignored by it. Put a flag under a")
+	fmt.Fprintln(w, "# [command] section, such as [preview], to set it for that command only.")
+
+	listed := map[string]bool{}
+	for _, group := range flagGroups {
+		fmt.Fprintf(w, "\n## %s\n", group.title)
+
+		for _, name := range group.names {
+			listed[name] = true
+			if f := fs.Lookup(name); f != nil && !contains(configSkipped, name) && !contains(configOnly, name) {
+				writeConfigFlag(w, f)
+			}
+		}
+	}
+
+	var other []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		if !listed[f.Name] && flagAliases[flagName(f.Name)] != f.Name && !contains(configSkipped, f.Name) && !contains(configOnly, f.Name) {
+			other = append(other, f)
+		}
+	})
+
+	if len(other) > 0 {
+		fmt.Fprintln(w, "\n## More flags")
+		for _, f := range other {
+			writeConfigFlag(w, f)
+		}
+	}
+}
+
+func writeConfigFlag(w io.Writer, f *flag.Flag) {
+	_, usage := flag.UnquoteUsage(f)
+
+	// durations and the like have no TOML type, they're written as strings
+	value := tomlString(f.DefValue)
+	switch v := f.Value.(type) {
+	case *stringList:
+		value = "[]"
+	case *verbosity:
+		value = "false"
+	case flag.Getter:
+		switch v.Get().(type) {
+		case bool, int, uint, int64, uint64, float64:
+			value = f.DefValue
+		}
+	}
+
+	fmt.Fprintf(w, "\n# %s\n# %s = %s\n", usage, f.Name, value)
+}
+
+// tomlString quotes s as a TOML string, as a literal one when it has
+// backslashes, like registry paths do, so they're written as they are.
+func tomlString(s string) string {
+	if strings.Contains(s, `\`) && !strings.ContainsAny(s, "'\n") {
+		return "'" + s + "'"
+	}
+
+	return strconv.Quote(s)
+}
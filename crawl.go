@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+const maxCrawlPages = 100
+
+var (
+	reHTMLLink     = regexp.MustCompile(`(?is)<a\b([^>]*)>(.*?)</a>`)
+	reDotshareDot  = regexp.MustCompile(`^(https?://(?:www\.)?dotshare\.it/dots/[0-9]+/)`)
+	reUnsafeInName = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f]+`)
+)
+
+// dotshareTheme is a theme linked from a dotshare.it listing page.
+type dotshareTheme struct {
+	url   string
+	title string
+}
+
+// dotshareListing returns the themes linked from a listing page, in the
+// order they appear, along with the URL of the next page, if any.
+func dotshareListing(pageURL string, page []byte) ([]dotshareTheme, string) {
+	var (
+		themes []dotshareTheme
+		next   string
+	)
+
+	seen := map[string]int{}
+
+	for _, m := range reHTMLLink.FindAllSubmatch(page, -1) {
+		attrs := xmlAttrs(string(m[1]))
+		text := strings.Join(strings.Fields(html.UnescapeString(reHTMLTag.ReplaceAllString(string(m[2]), ""))), " ")
+
+		href, err := resolveURL(pageURL, html.UnescapeString(attrs["href"]))
+		if err != nil || attrs["href"] == "" {
+			continue
+		}
+
+		label := strings.ToLower(text)
+		if strings.Contains(" "+attrs["rel"]+" ", " next ") || strings.HasPrefix(label, "next") || strings.HasPrefix(label, "older") || label == "»" {
+			next = href
+			continue
+		}
+
+		dot := reDotshareDot.FindString(href)
+		if dot == "" {
+			continue
+		}
+
+		if i, found := seen[dot]; found {
+			if themes[i].title == "" {
+				themes[i].title = text
+			}
+
+			continue
+		}
+
+		seen[dot] = len(themes)
+		themes = append(themes, dotshareTheme{url: dot, title: text})
+	}
+
+	return themes, next
+}
+
+// sanitizeSessionName cleans up a theme title so it can be used as a
+// session name, and as the name of the .reg file holding it.
+func sanitizeSessionName(title string) string {
+	return strings.Trim(strings.Join(strings.Fields(reUnsafeInName.ReplaceAllString(title, " ")), " "), ".")
+}
+
+// crawlDotshare converts every theme linked from a dotshare.it listing
+// page, following its pagination, into a file in the output directory
+// named after the theme title. Themes that fail to download or convert
+// are reported and skipped, and a summary is printed at the end. Listing
+// pages are never cached, so themes added since the last crawl are found.
+func crawlDotshare(listURL string, bt *batch) error {
+	var themes []dotshareTheme
+	visited, listed := map[string]bool{}, map[string]bool{}
+
+	for page := listURL; page != "" && !visited[page] && len(visited) < maxCrawlPages; {
+		visited[page] = true
+
+		file, err := download(page, cacheSkip)
+		if err != nil {
+			if len(visited) == 1 {
+				return err
+			}
+
+			warnf("stopping at page %d of the listing: %s", len(visited), err.Error())
+			break
+		}
+
+		found, next := dotshareListing(page, file.Body)
+		debugf("found %d themes in %s", len(found), page)
+
+		for _, theme := range found {
+			if !listed[theme.url] {
+				listed[theme.url] = true
+				themes = append(themes, theme)
+			}
+		}
+
+		page = next
+	}
+
+	if len(themes) == 0 {
+		return fmt.Errorf("no themes found in %q, it doesn't look like a dotshare.it listing page", listURL)
+	}
+
+	var converted, skipped, failed int
+
+	for _, theme := range themes {
+		page, err := download(theme.url, cacheRevalidate)
+		if err != nil {
+			warnf("%s", err.Error())
+			failed++
+			continue
+		}
+
+		path, err := convertTheme(theme, page.Body, bt)
+		if err != nil {
+			warnf("skipping %s: %s", theme.url, err.Error())
+			skipped++
+			continue
+		}
+
+		debugf("converted %s to %s", theme.url, path)
+		converted++
+	}
+
+	notef("converted %d of %d themes, %d skipped as they couldn't be parsed, %d failed to download", converted, len(themes), skipped, failed)
+
+	if converted == 0 {
+		return errors.New("none of the themes could be converted")
+	}
+
+	return bt.finish(listURL)
+}
+
+// convertTheme converts a single crawled theme, returning the path of the
+// file written.
+func convertTheme(theme dotshareTheme, page []byte, bt *batch) (string, error) {
+	lines, err := dotshareScheme(theme.url, page)
+	if err != nil {
+		return "", err
+	}
+
+	fallback := "dotshare " + strings.TrimSuffix(theme.url[strings.Index(theme.url, "/dots/")+6:], "/")
+	return bt.convertToFile(theme.url, lines, theme.title, fallback)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	reCSSPrefix = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+	reCSSClass  = regexp.MustCompile(`^-?[A-Za-z_][A-Za-z0-9_-]*$`)
+)
+
+// checkCSSOptions makes sure the prefix and class can be written as is
+// into a stylesheet.
+func checkCSSOptions(opts encodeOptions) error {
+	if !reCSSPrefix.MatchString(opts.cssPrefix) {
+		return fmt.Errorf("invalid --css-prefix %q: only letters, digits, \"-\" and \"_\" are allowed", opts.cssPrefix)
+	}
+
+	if opts.cssClass != "" && !reCSSClass.MatchString(opts.cssClass) {
+		return fmt.Errorf("invalid --css-class %q: not a valid CSS class name", opts.cssClass)
+	}
+
+	return nil
+}
+
+// writeCSS writes the colors as CSS custom properties, set on :root or on
+// the class given in the options, for web terminals such as ttyd.
+func writeCSS(w io.Writer, sname string, p palette, opts encodeOptions) error {
+	selector := ":root"
+	if opts.cssClass != "" {
+		selector = "." + opts.cssClass
+	}
+
+	fmt.Fprintf(w, "/* %s */\n", sanitizeCSSComment(sname))
+	fmt.Fprintf(w, "%s {\n", selector)
+
+	for _, name := range themeColors() {
+		fmt.Fprintf(w, "  --%s%s: %s;\n", opts.cssPrefix, name[0], hexColor(p.keys[name[1]]))
+	}
+
+	fmt.Fprintln(w, "}")
+
+	return nil
+}
+
+// sanitizeCSSComment keeps the session name from ending the comment it's
+// written in.
+func sanitizeCSSComment(s string) string {
+	return strings.ReplaceAll(s, "*/", "* /")
+}
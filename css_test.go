@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSS(t *testing.T) {
+	p := builtinTestPalette(t, "nord")
+
+	tests := []struct {
+		golden string
+		opts   encodeOptions
+	}{
+		{"nord.css", encodeOptions{cssPrefix: "term-"}},
+		{"nord.scoped.css", encodeOptions{cssPrefix: "ttyd-", cssClass: "nord"}},
+	}
+
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := writeCSS(&b, "Nord */ body { color: red }", p, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+
+		checkGolden(t, tt.golden, b.Bytes())
+	}
+}
+
+func TestCheckCSSOptions(t *testing.T) {
+	tests := []struct {
+		opts    encodeOptions
+		wantErr bool
+	}{
+		{encodeOptions{cssPrefix: "term-"}, false},
+		{encodeOptions{cssPrefix: ""}, false},
+		{encodeOptions{cssPrefix: "term-", cssClass: "-dark_theme2"}, false},
+		{encodeOptions{cssPrefix: "term;"}, true},
+		{encodeOptions{cssPrefix: "term-", cssClass: "2dark"}, true},
+		{encodeOptions{cssPrefix: "term-", cssClass: "dark theme"}, true},
+		{encodeOptions{cssPrefix: "term-", cssClass: "a{}"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := checkCSSOptions(tt.opts); (err != nil) != tt.wantErr {
+			t.Errorf("checkCSSOptions(%q, %q) = %v, want an error: %t", tt.opts.cssPrefix, tt.opts.cssClass, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// dconfProfile is a GNOME Terminal or Tilix profile from a dconf dump,
+// keyed by the settings it holds.
+type dconfProfile struct {
+	id       string
+	settings map[string]kvEntry
+}
+
+// name returns the visible name of the profile, or its id when unnamed.
+func (p dconfProfile) name() string {
+	if e, found := p.settings["visible-name"]; found && e.value != "" {
+		return e.value
+	}
+
+	return p.id
+}
+
+func detectDconf(content string) bool {
+	return strings.Contains(content, "palette=[") &&
+		(strings.Contains(content, "foreground-color=") || strings.Contains(content, "background-color="))
+}
+
+// dconfProfiles groups the settings of a dconf dump by section, keeping
+// only the sections that hold a palette. GNOME Terminal prefixes profile
+// ids with ":" while Tilix doesn't.
+func dconfProfiles(lines []sourceLine) []dconfProfile {
+	var profiles []dconfProfile
+	index := map[string]int{}
+
+	for _, e := range parseINI(lines) {
+		pos, found := index[e.section]
+		if !found {
+			pos = len(profiles)
+			index[e.section] = pos
+			profiles = append(profiles, dconfProfile{id: strings.TrimPrefix(e.section, ":"), settings: map[string]kvEntry{}})
+		}
+
+		profiles[pos].settings[e.key] = e
+	}
+
+	withPalette := profiles[:0]
+	for _, p := range profiles {
+		if _, found := p.settings["palette"]; found {
+			withPalette = append(withPalette, p)
+		}
+	}
+
+	return withPalette
+}
+
+// selectDconfProfile picks the profile to convert, selected by id or visible
+// name when the dump has more than one.
+func selectDconfProfile(lines []sourceLine, opts decodeOptions) (dconfProfile, error) {
+	profiles := dconfProfiles(lines)
+
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		if opts.profile != "" && (p.id == opts.profile || p.name() == opts.profile) {
+			return p, nil
+		}
+
+		names = append(names, fmt.Sprintf("%s (%s)", p.name(), p.id))
+	}
+
+	switch {
+	case len(profiles) == 0:
+		return dconfProfile{}, errors.New("no profile with a palette found in dconf dump")
+	case opts.profile != "":
+		return dconfProfile{}, fmt.Errorf("no profile named %q in dconf dump, available profiles: %s", opts.profile, strings.Join(names, ", "))
+	case len(profiles) > 1:
+		return dconfProfile{}, fmt.Errorf("dconf dump has %d profiles, pick one with --profile: %s", len(profiles), strings.Join(names, ", "))
+	}
+
+	return profiles[0], nil
+}
+
+// parseGVariantList returns the strings of a GVariant array such as
+// ['rgb(29,31,33)', '#cc6666'], where commas may appear inside the quoted
+// items.
+func parseGVariantList(s string) []string {
+	var (
+		items []string
+		quote byte
+		item  strings.Builder
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0 && c == '\\' && i+1 < len(s):
+			i++
+			item.WriteByte(s[i])
+		case quote != 0 && c == quote:
+			items = append(items, item.String())
+			item.Reset()
+			quote = 0
+		case quote != 0:
+			item.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+
+	return items
+}
+
+// parseDconf reads a GNOME Terminal or Tilix profile from the output of
+// "dconf dump". Colors are written as "rgb(r,g,b)" or hex, and the cursor
+// and bold colors are only used when the profile enables them.
+func parseDconf(lines []sourceLine, opts decodeOptions) (map[string]resource, error) {
+	profile, err := selectDconfProfile(lines, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]resource{}
+
+	set := func(key string, e kvEntry, value string) error {
+		c, err := parseColor(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid color for %s: %s", e.source, e.key, err.Error())
+		}
+
+		debugf("%s: using %s: %s", e.source, e.key, value)
+		values[key] = resource{value: hexColor(c), source: e.source}
+		return nil
+	}
+
+	palette := profile.settings["palette"]
+	colors := parseGVariantList(palette.value)
+	if len(colors) < 16 {
+		return nil, fmt.Errorf("%s: palette has %d colors, expected 16", palette.source, len(colors))
+	}
+
+	for i, value := range colors[:16] {
+		if err := set(fmt.Sprintf("color%d", i), palette, value); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := map[string]string{
+		"foreground-color":        "foreground",
+		"background-color":        "background",
+		"cursor-background-color": "cursorColor",
+		"cursor-foreground-color": "cursorColor2",
+		"bold-color":              "colorBD",
+	}
+
+	enabled := map[string]bool{
+		"cursor-background-color": profile.settings["cursor-colors-set"].value != "false",
+		"cursor-foreground-color": profile.settings["cursor-colors-set"].value != "false",
+		"bold-color":              profile.settings["bold-color-same-as-fg"].value == "false" || profile.settings["bold-color-set"].value == "true",
+	}
+
+	for field, key := range fields {
+		e, found := profile.settings[field]
+		if !found {
+			continue
+		}
+
+		if on, checked := enabled[field]; checked && !on {
+			debugf("%s: skipping %s, the profile doesn't enable it", e.source, field)
+			continue
+		}
+
+		if err := set(key, e, e.value); err != nil {
+			return nil, err
+		}
+	}
+
+	if e, found := profile.settings["use-theme-colors"]; found && e.value == "true" {
+		notef("profile %q uses the colors of the system theme, its own foreground and background may not be what you see", profile.name())
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "dconf profile")
+	return values, nil
+}
+
+// dconfName returns the visible name of the selected profile.
+func dconfName(lines []sourceLine, opts decodeOptions) string {
+	profile, err := selectDconfProfile(lines, opts)
+	if err != nil {
+		return ""
+	}
+
+	return profile.name()
+}
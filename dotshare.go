@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	reDotsharePage = regexp.MustCompile(`^https?://(?:www\.)?dotshare\.it/dots/[0-9]+/?$`)
+	reHTMLCode     = regexp.MustCompile(`(?is)<(pre|textarea)\b[^>]*>(.*?)</(?:pre|textarea)>`)
+	reHTMLTag      = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// isDotshareURL reports whether the URL is a dotshare.it theme page, such
+// as the ones linked from the usage message, rather than a raw file.
+func isDotshareURL(name string) bool {
+	return reDotsharePage.MatchString(name)
+}
+
+// htmlCodeBlocks returns the text of the <pre> and <textarea> blocks of
+// an HTML page, with any highlighting markup removed.
+func htmlCodeBlocks(page []byte) []string {
+	var blocks []string
+	for _, m := range reHTMLCode.FindAllSubmatch(page, -1) {
+		text := html.UnescapeString(reHTMLTag.ReplaceAllString(string(m[2]), ""))
+		if strings.TrimSpace(text) != "" {
+			blocks = append(blocks, text)
+		}
+	}
+
+	return blocks
+}
+
+// readDotshare downloads a dotshare.it theme page and returns the lines of
+// the first code block holding a color scheme that converts, since pages
+// often carry other dotfiles next to the colors.
+func readDotshare(pageURL string) ([]sourceLine, error) {
+	page, err := download(pageURL, cacheRevalidate)
+	if err != nil {
+		return nil, err
+	}
+
+	return dotshareScheme(pageURL, page.Body)
+}
+
+// dotshareScheme picks the color scheme out of a downloaded dotshare.it
+// theme page: the first code block that can be decoded and converted,
+// skipping the ones, such as comments only, that would fail.
+func dotshareScheme(pageURL string, page []byte) ([]sourceLine, error) {
+	blocks := htmlCodeBlocks(page)
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no code blocks found in dotshare.it page %q", pageURL)
+	}
+
+	tried := make([]string, 0, len(blocks))
+	for i, block := range blocks {
+		lines, err := scanLines(pageURL, bytes.NewReader([]byte(block)))
+		if err != nil {
+			return nil, err
+		}
+
+		values, _, err := decodeLines(pageURL, lines, "", decodeOptions{})
+		if err == nil {
+			_, err = convert(values)
+		}
+
+		if err == nil {
+			debugf("using code block %d of %d from %s", i+1, len(blocks), pageURL)
+			return lines, nil
+		}
+
+		debugf("skipping code block %d of %d from %s: %s", i+1, len(blocks), pageURL, err.Error())
+		tried = append(tried, fmt.Sprintf("block %d (%q)", i+1, firstLine(block)))
+	}
+
+	return nil, fmt.Errorf("none of the code blocks in dotshare.it page %q hold a color scheme that converts, tried: %s", pageURL, strings.Join(tried, ", "))
+}
+
+// firstLine returns the first non-empty line of text, shortened so it can
+// be quoted in a message.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if len(line) > 40 {
+				line = line[:40] + "..."
+			}
+
+			return line
+		}
+	}
+
+	return ""
+}
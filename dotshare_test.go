@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDotshareScheme(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join("testdata", "dotshare-page.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const pageURL = "http://dotshare.it/dots/1234/"
+
+	lines, err := dotshareScheme(pageURL, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first block is only comments, and the second a zsh config
+	values, _, err := decodeLines(pageURL, lines, "", decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := hexColor(p.keys["background"]); got != "#1d1f21" {
+		t.Errorf("background = %s, want #1d1f21 from the third block", got)
+	}
+
+	_, err = dotshareScheme(pageURL, []byte("<pre>! colors coming soon</pre><pre>export PS1='$ '</pre>"))
+	if err == nil || !strings.Contains(err.Error(), `block 1 ("! colors coming soon"), block 2 ("export PS1='$ '")`) {
+		t.Errorf("dotshareScheme() = %v, want an error listing the blocks tried", err)
+	}
+}
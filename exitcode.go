@@ -0,0 +1,59 @@
+package main
+
+import "errors"
+
+// The exit statuses of the tool, so scripts can tell failures apart
+// without matching the error messages. --diff-registry has its own, see
+// exitDiffError.
+const (
+	exitUsage       = 1 // invalid flags or arguments
+	exitInput       = 2 // an input file that doesn't exist or can't be read
+	exitParse       = 3 // an input without colors, or with invalid values
+	exitMissingKeys = 4 // an input missing some of the required colors
+	exitOutput      = 5 // an output that can't be written
+)
+
+// exitDiffError is the exit status of every error found with
+// --diff-registry once the flags are parsed, whatever its class, as it
+// exits with 1 when the session differs from the theme.
+const exitDiffError = 2
+
+// diffing is set with --diff-registry, for exitStatus to use
+// exitDiffError.
+var diffing bool
+
+// codedError is an error carrying the exit status it's reported with.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+func (e *codedError) Code() int     { return e.code }
+
+// withCode classifies err for the exit status, leaving nil and already
+// classified errors alone.
+func withCode(code int, err error) error {
+	var coded *codedError
+	if err == nil || errors.As(err, &coded) {
+		return err
+	}
+
+	return &codedError{code: code, err: err}
+}
+
+// exitStatus returns the exit status to report err with. Errors that
+// aren't classified are usage errors.
+func exitStatus(err error) int {
+	if diffing {
+		return exitDiffError
+	}
+
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.Code()
+	}
+
+	return exitUsage
+}
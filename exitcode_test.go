@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitStatus(t *testing.T) {
+	theme := filepath.Join("testdata", "mixed-case.Xresources")
+
+	existing := filepath.Join(t.TempDir(), "existing.reg")
+	if err := os.WriteFile(existing, []byte("keep me\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"unknown flag", []string{theme, "Work", "--bogus"}, exitUsage},
+		{"too many arguments for --theme", []string{"--theme", "nord", theme, "Work"}, exitUsage},
+		{"unknown output format", []string{theme, "Work", "--to", "bogus"}, exitUsage},
+		{"missing input", []string{filepath.Join("testdata", "missing.Xresources"), "Work"}, exitInput},
+		{"invalid color", []string{filepath.Join("testdata", "invalid-shorthand.Xresources"), "Work"}, exitParse},
+		{"no colors", []string{os.DevNull, "Work"}, exitParse},
+		{"missing keys", []string{filepath.Join("testdata", "missing-keys.Xresources"), "Work"}, exitMissingKeys},
+		{"existing output", []string{theme, "Work", "--output", existing}, exitOutput},
+		{"output in a missing directory", []string{theme, "Work", "--output", filepath.Join(t.TempDir(), "missing", "out.reg")}, exitOutput},
+		{"invalid color when diffing", []string{filepath.Join("testdata", "invalid-shorthand.Xresources"), "Work", "--diff-registry"}, exitDiffError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFlags(t)
+			t.Cleanup(func() { diffing = false })
+
+			err := run(tt.args)
+			if err == nil {
+				t.Fatal("run() succeeded, want an error")
+			}
+
+			if got := exitStatus(err); got != tt.want {
+				t.Errorf("run() = %q, exiting with %d, want %d", err, got, tt.want)
+			}
+		})
+	}
+
+	if got, err := os.ReadFile(existing); err != nil || string(got) != "keep me\n" {
+		t.Errorf("the existing output was overwritten with %q, %v", got, err)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	retryDelay   = 500 * time.Millisecond
+	maxFetchSize = 1 << 20
+	userAgent    = "urxvt-kitty (+https://github.com/patrickdappollonio/urxvt-kitty)"
+)
+
+// fetchOptions are the settings used when downloading themes, set from
+// the command line flags.
+type fetchOptions struct {
+	// insecure disables TLS certificate verification, for internal
+	// servers with self-signed certificates.
+	insecure bool
+
+	// noCache skips the download cache entirely, while refresh still uses
+	// it but checks with the server whether the cached copy is current.
+	noCache bool
+	refresh bool
+
+	// timeout limits each download attempt, and retries is how many more
+	// attempts are made after a transient failure.
+	timeout time.Duration
+	retries int
+
+	// ctx is cancelled on Ctrl-C, aborting in-flight downloads.
+	ctx context.Context
+}
+
+var fetchOpts = fetchOptions{timeout: 10 * time.Second, retries: 2}
+
+// cachePolicy is how download uses the download cache for a URL.
+type cachePolicy int
+
+const (
+	// cacheRevalidate reuses the cached copy only once the server reports
+	// it hasn't changed, for URLs whose contents can change at any time.
+	cacheRevalidate cachePolicy = iota
+
+	// cacheReuse reuses the cached copy without asking the server, unless
+	// --refresh is given, for the "github:" shorthand, whose files are
+	// converted over and over.
+	cacheReuse
+
+	// cacheSkip neither reads nor stores a copy, for pages such as crawl
+	// listings that are only useful when current.
+	cacheSkip
+)
+
+// remoteFile is a downloaded file, as returned by download and as stored
+// in the download cache.
+type remoteFile struct {
+	URL       string `json:"url"`
+	MediaType string `json:"media_type"`
+	ETag      string `json:"etag,omitempty"`
+	Modified  string `json:"last_modified,omitempty"`
+	Body      []byte `json:"body"`
+}
+
+// isURL reports whether the input name is an http or https URL rather
+// than a file name.
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// fetchURL downloads the theme at the given URL. Since the body is parsed
+// as a theme file, HTML pages are rejected, which usually means the URL
+// points to a page showing the file rather than the raw file itself, or to
+// a login page the request got redirected to.
+func fetchURL(rawURL string, policy cachePolicy) ([]byte, error) {
+	file, err := download(rawURL, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.MediaType == "text/html" {
+		if file.URL != rawURL {
+			return nil, fmt.Errorf("can't download %q: it redirected to the HTML page %q, which might be a login page, use a link to the raw file instead", rawURL, file.URL)
+		}
+
+		return nil, fmt.Errorf("can't download %q: it's an HTML page rather than a theme file, use a link to the raw file instead", rawURL)
+	}
+
+	return file.Body, nil
+}
+
+// download fetches the given URL, using the download cache as the policy
+// says: a cached copy is reused as is only with cacheReuse and without
+// --refresh, and otherwise only if the server reports it hasn't changed
+// since. Connection resets, timeouts and server errors are retried with a
+// growing delay between attempts.
+func download(rawURL string, policy cachePolicy) (remoteFile, error) {
+	var (
+		cached   remoteFile
+		isCached bool
+	)
+
+	if policy != cacheSkip {
+		cached, isCached = readCache(rawURL)
+	}
+
+	if isCached && policy == cacheReuse && !fetchOpts.refresh {
+		debugf("using the cached copy of %s", rawURL)
+		return cached, nil
+	}
+
+	ctx := fetchOpts.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	client := &http.Client{Timeout: fetchOpts.timeout}
+	if fetchOpts.insecure {
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return remoteFile{}, fmt.Errorf("invalid URL %q: %s", rawURL, err.Error())
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+	if isCached && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	if isCached && cached.Modified != "" {
+		req.Header.Set("If-Modified-Since", cached.Modified)
+	}
+
+	var (
+		attempt int
+		lastErr error
+	)
+
+	for attempt = 1; attempt <= fetchOpts.retries+1; attempt++ {
+		if attempt > 1 {
+			delay := retryDelay << (attempt - 2)
+			debugf("retrying the download of %s in %s: %s", rawURL, delay, lastErr.Error())
+
+			if err := retryWait(ctx, delay); err != nil {
+				return remoteFile{}, fmt.Errorf("download of %q cancelled", rawURL)
+			}
+		}
+
+		debugf("downloading %s", rawURL)
+
+		file, status, err := fetchOnce(client, req)
+		switch {
+		case ctx.Err() != nil:
+			return remoteFile{}, fmt.Errorf("download of %q cancelled", rawURL)
+		case err == nil && status == http.StatusNotModified && isCached:
+			debugf("the cached copy of %s is up to date", rawURL)
+			return cached, nil
+		case err == nil && status == http.StatusOK:
+			if policy != cacheSkip {
+				writeCache(rawURL, file)
+			}
+
+			return file, nil
+		case err == nil:
+			lastErr = fmt.Errorf("server responded with %d %s", status, http.StatusText(status))
+			if status < 500 {
+				return remoteFile{}, fmt.Errorf("can't download %q: %s", rawURL, lastErr.Error())
+			}
+		default:
+			lastErr = err
+			if !isTransient(err) {
+				return remoteFile{}, fmt.Errorf("can't download %q: %s", rawURL, err.Error())
+			}
+		}
+	}
+
+	if attempt--; attempt == 1 {
+		return remoteFile{}, fmt.Errorf("can't download %q: %s", rawURL, lastErr.Error())
+	}
+
+	return remoteFile{}, fmt.Errorf("can't download %q after %d attempts: %s", rawURL, attempt, lastErr.Error())
+}
+
+// retryWait waits for the delay before retrying a download, or until the
+// context is cancelled, returning its error. Tests replace it to retry
+// without waiting.
+var retryWait = func(ctx context.Context, delay time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// fetchOnce sends the request, returning the downloaded file on success
+// along with the response status.
+func fetchOnce(client *http.Client, req *http.Request) (remoteFile, int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return remoteFile{}, 0, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return remoteFile{}, resp.StatusCode, nil
+	}
+
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, io.LimitReader(resp.Body, maxFetchSize+1)); err != nil {
+		return remoteFile{}, 0, err
+	}
+
+	if b.Len() > maxFetchSize {
+		return remoteFile{}, 0, fmt.Errorf("it's larger than the %d KiB limit for theme files", maxFetchSize>>10)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	file := remoteFile{
+		URL:       resp.Request.URL.String(),
+		MediaType: mediaType,
+		ETag:      resp.Header.Get("ETag"),
+		Modified:  resp.Header.Get("Last-Modified"),
+		Body:      b.Bytes(),
+	}
+
+	return file, resp.StatusCode, nil
+}
+
+// isTransient reports whether the download error is worth retrying.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// resolveURL resolves a reference, such as an included file, relative to
+// the URL of the file referencing it.
+func resolveURL(base, ref string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return u.ResolveReference(r).String(), nil
+}
+
+// expandGitHub expands the "github:owner/repo/path@ref" shorthand to the
+// URL of the raw file. Without a ref, the default branch is used.
+func expandGitHub(name string) (string, bool) {
+	if !strings.HasPrefix(name, "github:") {
+		return name, false
+	}
+
+	path, ref := strings.TrimPrefix(name, "github:"), "HEAD"
+	if pos := strings.LastIndex(path, "@"); pos >= 0 {
+		path, ref = path[:pos], path[pos+1:]
+	}
+
+	parts := strings.SplitN(strings.Trim(path, "/"), "/", 3)
+	if len(parts) != 3 || ref == "" {
+		return name, false
+	}
+
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", parts[0], parts[1], ref, parts[2]), true
+}
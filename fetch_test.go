@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer serves the theme once it has failed the given number of
+// requests with the status, counting the requests it got.
+func flakyServer(t *testing.T, failures int, status int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt32(&requests, 1); int(n) <= failures {
+			http.Error(w, "try again later", status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("*.foreground: #c5c8c6\n"))
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+// withRetries sets the download options for the test, recording the
+// delays waited before each retry instead of waiting.
+func withRetries(t *testing.T, retries int) *[]time.Duration {
+	t.Helper()
+
+	var delays []time.Duration
+	prevOpts, prevWait := fetchOpts, retryWait
+
+	fetchOpts = fetchOptions{noCache: true, timeout: 5 * time.Second, retries: retries}
+	retryWait = func(ctx context.Context, delay time.Duration) error {
+		delays = append(delays, delay)
+		return ctx.Err()
+	}
+
+	t.Cleanup(func() { fetchOpts, retryWait = prevOpts, prevWait })
+	return &delays
+}
+
+func TestDownloadRetries(t *testing.T) {
+	delays := withRetries(t, 2)
+	srv, requests := flakyServer(t, 2, http.StatusServiceUnavailable)
+
+	file, err := download(srv.URL+"/theme.Xresources", cacheRevalidate)
+	if err != nil {
+		t.Fatalf("download() = %s, want it to succeed on the third attempt", err)
+	}
+
+	if !strings.Contains(string(file.Body), "#c5c8c6") {
+		t.Errorf("download() body = %q", file.Body)
+	}
+
+	if *requests != 3 {
+		t.Errorf("got %d requests, want 3", *requests)
+	}
+
+	want := []time.Duration{retryDelay, 2 * retryDelay}
+	if len(*delays) != len(want) || (*delays)[0] != want[0] || (*delays)[1] != want[1] {
+		t.Errorf("waited %v between attempts, want the growing delays %v", *delays, want)
+	}
+}
+
+func TestDownloadGivesUp(t *testing.T) {
+	delays := withRetries(t, 2)
+	srv, requests := flakyServer(t, 10, http.StatusBadGateway)
+
+	_, err := download(srv.URL+"/theme.Xresources", cacheRevalidate)
+	if err == nil {
+		t.Fatal("download() succeeded, want it to give up")
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "after 3 attempts") || !strings.Contains(msg, "502") {
+		t.Errorf("download() = %q, want the attempt count and the last status", msg)
+	}
+
+	if *requests != 3 || len(*delays) != 2 {
+		t.Errorf("got %d requests and %d waits, want 3 and 2", *requests, len(*delays))
+	}
+}
+
+func TestDownloadClientErrorNotRetried(t *testing.T) {
+	delays := withRetries(t, 2)
+	srv, requests := flakyServer(t, 10, http.StatusNotFound)
+
+	_, err := download(srv.URL+"/missing.Xresources", cacheRevalidate)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("download() = %v, want a 404 error", err)
+	}
+
+	if *requests != 1 || len(*delays) != 0 {
+		t.Errorf("got %d requests and %d waits, want a single attempt", *requests, len(*delays))
+	}
+}
+
+func TestDownloadCancelled(t *testing.T) {
+	withRetries(t, 2)
+	srv, _ := flakyServer(t, 10, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fetchOpts.ctx = ctx
+
+	_, err := download(srv.URL+"/theme.Xresources", cacheRevalidate)
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("download() = %v, want it cancelled", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func detectFoot(content string) bool {
+	return strings.Contains(content, "[colors]") && strings.Contains(content, "regular0")
+}
+
+// parseFoot reads the [colors] section of a foot.ini file. foot writes
+// colors as hex without the leading "#", and its cursor colors, when set,
+// live in the [cursor] section as "color=<text> <cursor>".
+func parseFoot(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+	hasColors := false
+
+	set := func(key string, e kvEntry, value string) {
+		if !strings.HasPrefix(value, "#") {
+			value = "#" + value
+		}
+
+		debugf("%s: using [%s] %s: %s", e.source, e.section, e.key, value)
+		values[key] = resource{value: value, source: e.source}
+	}
+
+	for _, e := range parseINI(lines) {
+		switch e.section {
+		case "colors":
+			hasColors = true
+		case "cursor":
+			if fields := strings.Fields(e.value); e.key == "color" && len(fields) == 2 {
+				set("cursorColor2", e, fields[0])
+				set("cursorColor", e, fields[1])
+			}
+
+			continue
+		default:
+			continue
+		}
+
+		switch {
+		case e.key == "foreground" || e.key == "background":
+			set(e.key, e, e.value)
+		case e.key == "selection-foreground":
+			set("highlightTextColor", e, e.value)
+		case e.key == "selection-background":
+			set("highlightColor", e, e.value)
+		case strings.HasPrefix(e.key, "regular"), strings.HasPrefix(e.key, "bright"):
+			offset, index := 0, strings.TrimPrefix(e.key, "regular")
+			if strings.HasPrefix(e.key, "bright") {
+				offset, index = 8, strings.TrimPrefix(e.key, "bright")
+			}
+
+			n, err := strconv.Atoi(index)
+			if err != nil || n > 7 {
+				continue
+			}
+
+			set(fmt.Sprintf("color%d", n+offset), e, e.value)
+		}
+	}
+
+	if !hasColors {
+		return nil, errors.New("no [colors] section found in foot configuration")
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "foot configuration")
+	return values, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// decodeOptions are the settings shared by all the input decoders.
+type decodeOptions struct {
+	includes bool
+	scheme   string
+	profile  string
+}
+
+// inputFormat is a theme file format that can be read into resources
+// keyed by their Xresources names.
+type inputFormat struct {
+	name   string
+	decode func(lines []sourceLine, opts decodeOptions) (map[string]resource, error)
+	detect func(content string) bool
+
+	// fileNames, when set, are the base names of files known to be in this
+	// format, detected by name before looking at the content.
+	fileNames []string
+
+	// themeName, when set, returns the name of the theme as stored in the
+	// file, used as the session name when none is given.
+	themeName func(lines []sourceLine, opts decodeOptions) string
+
+	// partial is set for formats whose files can hold only some of the
+	// colors, as a session saved with a few of them does. Their decoders
+	// warn about the missing colors, which convert then leaves out.
+	partial bool
+}
+
+// inputFormats lists the supported input formats sorted by name, which is
+// the order they are tried in when detecting the format of an input file.
+var inputFormats = []inputFormat{
+	{name: "alacritty", decode: parseAlacritty, detect: detectAlacritty},
+	{name: "base16", decode: parseBase16, detect: detectBase16, themeName: base16Name},
+	{name: "conemu", decode: parseConEmu, detect: detectConEmu, themeName: conEmuName},
+	{name: "dconf", decode: parseDconf, detect: detectDconf, themeName: dconfName},
+	{name: "foot", decode: parseFoot, detect: detectFoot},
+	{name: "gogh", decode: parseGogh, detect: detectGogh, themeName: goghName},
+	{name: "hyper", decode: parseHyper, detect: detectHyper},
+	{name: "iterm", decode: parseIterm, detect: detectIterm},
+	{name: "kitty-conf", decode: parseKittyConf, detect: detectKittyConf},
+	{name: "konsole", decode: parseKonsole, detect: detectKonsole},
+	{name: "mintty", decode: parseMintty, detect: detectMintty},
+	{name: "pywal", decode: parsePywal, detect: detectPywal},
+	{name: "reg", decode: parseReg, detect: detectReg, themeName: regName, partial: true},
+	{name: "rxvt-args", decode: parseRxvtArgs, detect: detectRxvtArgs},
+	{name: "st", decode: parseSt, detect: detectSt},
+	{name: "terminalsexy", decode: parseTerminalSexy, detect: detectTerminalSexy},
+	{name: "terminator", decode: parseTerminator, detect: detectTerminator, themeName: terminatorName},
+	{name: "termite", decode: parseTermite, detect: detectTermite},
+	{name: "termux", decode: parseTermux, detect: detectTermux, fileNames: []string{"colors.properties"}},
+	{name: "vscode", decode: parseVSCode, detect: detectVSCode},
+	{name: "wezterm", decode: parseWezterm, detect: detectWezterm, themeName: weztermName},
+	{name: "windows-terminal", decode: parseWindowsTerminal, detect: detectWindowsTerminal, themeName: windowsTerminalName},
+	{name: "xfce", decode: parseXfce, detect: detectXfce},
+	{name: "xresources", decode: parseXresources, detect: detectXresources},
+}
+
+func inputFormatNames() []string {
+	names := make([]string, 0, len(inputFormats))
+	for _, f := range inputFormats {
+		names = append(names, f.name)
+	}
+
+	return names
+}
+
+// findInputFormat returns the input format with the given name, or, if
+// the name is empty, the first one whose file names or detection match the
+// lines. Input with nothing but blank and comment lines is read as
+// Xresources, to report that no colors were found. When none of them
+// match otherwise, the error lists the formats tried.
+func findInputFormat(name string, lines []sourceLine) (inputFormat, error) {
+	if name == "" && len(lines) > 0 {
+		base := filepath.Base(lines[0].file)
+		for _, f := range inputFormats {
+			if contains(f.fileNames, base) {
+				return f, nil
+			}
+		}
+	}
+
+	if name == "" {
+		content := joinLines(lines)
+		for _, f := range inputFormats {
+			if f.detect(content) {
+				return f, nil
+			}
+		}
+
+		if !isBlank(lines) {
+			return inputFormat{}, fmt.Errorf("unable to detect the input format, tried: %s -- pick one with --from", strings.Join(inputFormatNames(), ", "))
+		}
+
+		name = "xresources"
+	}
+
+	for _, f := range inputFormats {
+		if f.name == name {
+			return f, nil
+		}
+	}
+
+	return inputFormat{}, fmt.Errorf("unknown input format %q, supported formats are: %s", name, strings.Join(inputFormatNames(), ", "))
+}
+
+// isBlank reports whether the lines are all empty or commented out.
+func isBlank(lines []sourceLine) bool {
+	for _, line := range lines {
+		if text := strings.TrimSpace(line.text); text != "" && !isComment(text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fallbackKey copies the value of the from key into key when the format
+// has no way to express key, letting the user know about it.
+func fallbackKey(values map[string]resource, key, from, format string) {
+	if _, found := values[key]; found {
+		return
+	}
+
+	if res, found := values[from]; found {
+		notef("%s has no %s, using %s instead", format, key, from)
+		values[key] = res
+	}
+}
+
+// fieldSource returns a source pointing at the file the lines came from,
+// without a line number, for formats where values aren't tied to a line.
+func fieldSource(lines []sourceLine, field string) sourceLine {
+	src := sourceLine{text: field}
+	if len(lines) > 0 {
+		src.file = lines[0].file
+	}
+
+	return src
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFindInputFormatBlank(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"empty", ""},
+		{"blank lines", "\n\n   \n\t\n"},
+		{"comments", "! Tomorrow Night\n! *.color0: #1d1f21\n\n// *.color1: #cc6666\n"},
+	}
+
+	for _, tt := range tests {
+		lines, err := scanLines("stdin", strings.NewReader(tt.content))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+
+		f, err := findInputFormat("", lines)
+		if err != nil {
+			t.Fatalf("%s: findInputFormat() = %s, want the xresources fallback", tt.name, err)
+		}
+
+		if f.name != "xresources" {
+			t.Errorf("%s: findInputFormat() = %s, want xresources", tt.name, f.name)
+		}
+
+		values, err := f.decode(lines, decodeOptions{})
+		if err != nil {
+			t.Fatalf("%s: decode() = %s", tt.name, err)
+		}
+
+		if len(values) != 0 {
+			t.Errorf("%s: decode() found %d values, want none", tt.name, len(values))
+		}
+	}
+}
+
+func TestFindInputFormatUnknown(t *testing.T) {
+	lines, err := scanLines("notes.txt", strings.NewReader("just some notes\nabout nothing\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = findInputFormat("", lines)
+	if err == nil || !strings.Contains(err.Error(), "unable to detect the input format") || !strings.Contains(err.Error(), "--from") {
+		t.Errorf("findInputFormat() = %v, want an error listing the formats tried", err)
+	}
+}
+
+func TestFindInputFormatByName(t *testing.T) {
+	if f, err := findInputFormat("base16", nil); err != nil || f.name != "base16" {
+		t.Errorf(`findInputFormat("base16") = %q, %v`, f.name, err)
+	}
+
+	if _, err := findInputFormat("nope", nil); err == nil || !strings.Contains(err.Error(), `unknown input format "nope"`) {
+		t.Errorf(`findInputFormat("nope") = %v, want an unknown format error`, err)
+	}
+}
+
+func TestInputFormatsOrder(t *testing.T) {
+	names := inputFormatNames()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("input formats aren't sorted by name, they're tried in this order: %s", strings.Join(names, ", "))
+	}
+}
+
+func TestDetectRxvtArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"options", "urxvt -fg '#c5c8c6' -bg '#1d1f21' --color0 '#282a2e'\n", true},
+		{"launcher script", "#!/bin/sh\nexec urxvt \\\n  -fg '#c5c8c6' \\\n  -bg '#1d1f21' \"$@\"\n", true},
+		{"commented options", "! urxvt -bg #000 -fg #fff\n", false},
+		{"xresources", "*.foreground: #c5c8c6\n*.background: #1d1f21\n", false},
+	}
+
+	for _, tt := range tests {
+		if got := detectRxvtArgs(tt.content); got != tt.want {
+			t.Errorf("%s: detectRxvtArgs() = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestXresourcesWithLaunchComment(t *testing.T) {
+	values := readTestValues(t, "launch-comment.Xresources")
+
+	if got := values["foreground"].format; got != "xresources" {
+		t.Fatalf("read as %q, want xresources", got)
+	}
+
+	for key, value := range map[string]string{"foreground": "#c5c8c6", "background": "#1d1f21", "color1": "#cc6666"} {
+		if got := values[key].value; got != value {
+			t.Errorf("%s = %q, want %q from the resources rather than the comment", key, got, value)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reGoghAssign = regexp.MustCompile(`^[ \t]*(?:export[ \t]+)?(COLOR_[0-9]{2}|FOREGROUND_COLOR|BACKGROUND_COLOR|CURSOR_COLOR|PROFILE_NAME)=(?:"([^"]*)"|'([^']*)'|([^\s;]*))`)
+	reGoghRef    = regexp.MustCompile(`^\$\{?([A-Z_0-9]+)\}?$`)
+)
+
+// goghVariables maps the Gogh theme variables to the Xresources keys they
+// set. COLOR_01 through COLOR_16 are added in init.
+var goghVariables = map[string]string{
+	"FOREGROUND_COLOR": "foreground",
+	"BACKGROUND_COLOR": "background",
+	"CURSOR_COLOR":     "cursorColor",
+}
+
+func init() {
+	for i := 1; i <= 16; i++ {
+		goghVariables[fmt.Sprintf("COLOR_%02d", i)] = "color" + strconv.Itoa(i-1)
+	}
+}
+
+func detectGogh(content string) bool {
+	return strings.Contains(content, "COLOR_01=")
+}
+
+// parseGoghVariables extracts the variable assignments of a Gogh theme
+// script without executing it, resolving values that reference another
+// variable, like the common CURSOR_COLOR="$FOREGROUND_COLOR".
+func parseGoghVariables(lines []sourceLine) map[string]resource {
+	vars := map[string]resource{}
+
+	for _, line := range lines {
+		m := reGoghAssign.FindStringSubmatch(line.text)
+		if m == nil {
+			continue
+		}
+
+		vars[m[1]] = resource{value: m[2] + m[3] + m[4], source: line}
+	}
+
+	for name, res := range vars {
+		for depth := 0; depth < maxMacroDepth; depth++ {
+			ref := reGoghRef.FindStringSubmatch(res.value)
+			if ref == nil {
+				break
+			}
+
+			target, found := vars[ref[1]]
+			if !found {
+				break
+			}
+
+			res.value = target.value
+		}
+
+		vars[name] = res
+	}
+
+	return vars
+}
+
+// parseGogh reads a Gogh theme shell script.
+func parseGogh(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+
+	for name, res := range parseGoghVariables(lines) {
+		if key, found := goghVariables[name]; found {
+			debugf("%s: using %s: %s", res.source, name, res.value)
+			values[key] = res
+		}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "Gogh theme")
+	return values, nil
+}
+
+// goghName returns the PROFILE_NAME of a Gogh theme.
+func goghName(lines []sourceLine, _ decodeOptions) string {
+	return parseGoghVariables(lines)["PROFILE_NAME"].value
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeGPL writes the colors as a GIMP palette, also read by Inkscape and
+// Krita, with each color named after the terminal color it comes from.
+func writeGPL(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	fmt.Fprintln(w, "GIMP Palette")
+	fmt.Fprintf(w, "Name: %s\n", strings.ReplaceAll(sname, "\n", " "))
+	fmt.Fprintln(w, "Columns: 8")
+	fmt.Fprintln(w, "#")
+
+	for _, name := range themeColors() {
+		c := p.keys[name[1]]
+		fmt.Fprintf(w, "%3d %3d %3d\t%s\n", c.R, c.G, c.B, name[0])
+	}
+
+	return nil
+}
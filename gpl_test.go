@@ -0,0 +1,18 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteGPL(t *testing.T) {
+	// a light theme, so the channels go from one to three digits
+	p := builtinTestPalette(t, "gruvbox-light")
+
+	var b bytes.Buffer
+	if err := writeGPL(&b, "Gruvbox\nLight", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "gruvbox-light.gpl", b.Bytes())
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"image/color"
+	"io"
+)
+
+// htmlPage renders the preview of one or more themes, self-contained so
+// it can be shared as a single file.
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; background: #f4f4f4; color: #222; }
+section { margin-bottom: 3em; }
+.term { font-family: monospace; padding: 1em; border-radius: 6px; white-space: pre; line-height: 1.4; max-width: 52em; }
+.swatches { display: grid; grid-template-columns: repeat(8, 7em); gap: 4px; margin-top: 1em; }
+.swatch { height: 4.5em; border-radius: 4px; padding: 4px; box-sizing: border-box; font: 11px monospace; display: flex; flex-direction: column; justify-content: flex-end; }
+</style>
+</head>
+<body>
+{{- range .Themes}}
+<section>
+<h2>{{.Name}}</h2>
+<div class="term" style="background: {{.Background.Hex}}; color: {{.Foreground.Hex}}">
+{{- with .Colors}}<span style="color: {{(index . 10).Hex}}">user@host</span>:<span style="color: {{(index . 12).Hex}}">~/src</span>$ ls
+<span style="color: {{(index . 4).Hex}}">docs</span>  <span style="color: {{(index . 2).Hex}}">build.sh</span>  main.go  <span style="color: {{(index . 6).Hex}}">latest</span>
+<span style="color: {{(index . 10).Hex}}">user@host</span>:<span style="color: {{(index . 12).Hex}}">~/src</span>$ git status --short
+<span style="color: {{(index . 1).Hex}}"> M</span> main.go
+<span style="color: {{(index . 2).Hex}}">A </span> docs/index.md
+<span style="color: {{(index . 3).Hex}}">warning:</span> <span style="color: {{(index . 5).Hex}}">LF</span> will be replaced by <span style="color: {{(index . 13).Hex}}">CRLF</span>
+{{range .}}<span style="color: {{.Hex}}">&#9608;&#9608;</span>{{end}}
+{{- end}}
+<span style="color: {{(index .Colors 10).Hex}}">user@host</span>:<span style="color: {{(index .Colors 12).Hex}}">~/src</span>$ <span style="background: {{.Cursor.Hex}}"> </span></div>
+<div class="swatches">
+{{- range .Special}}
+<div class="swatch" style="background: {{.Hex}}; color: {{.Label}}">{{.Name}}<br>{{.Hex}}</div>
+{{- end}}
+</div>
+<div class="swatches">
+{{- range .Colors}}
+<div class="swatch" style="background: {{.Hex}}; color: {{.Label}}">{{.Name}}<br>{{.Hex}}</div>
+{{- end}}
+</div>
+</section>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlSwatch is a color as shown by the preview, with a label color that
+// stays readable on top of it.
+type htmlSwatch struct {
+	Name  string
+	Hex   string
+	Label string
+}
+
+func newHTMLSwatch(name string, c color.RGBA) htmlSwatch {
+	label := "#ffffff"
+	if isLight(c) {
+		label = "#000000"
+	}
+
+	return htmlSwatch{Name: name, Hex: hexColor(c), Label: label}
+}
+
+// htmlTheme is a theme as shown by the preview.
+type htmlTheme struct {
+	Name                           string
+	Foreground, Background, Cursor htmlSwatch
+	Special                        []htmlSwatch
+	Colors                         []htmlSwatch
+}
+
+// writeHTML writes an HTML page previewing the theme: a terminal with
+// some sample output in its colors, followed by swatches of each color.
+func writeHTML(w io.Writer, sname string, p palette, opts encodeOptions) error {
+	return writeHTMLPage(w, sname, []namedPalette{{name: sname, palette: p}}, opts)
+}
+
+// writeHTMLPage writes a single HTML page previewing all the themes, used
+// by the batch modes.
+func writeHTMLPage(w io.Writer, title string, themes []namedPalette, _ encodeOptions) error {
+	data := struct {
+		Title  string
+		Themes []htmlTheme
+	}{Title: title}
+
+	for _, theme := range themes {
+		t := htmlTheme{Name: theme.name}
+		for _, name := range themeColors() {
+			swatch := newHTMLSwatch(name[0], theme.palette.keys[name[1]])
+
+			switch name[0] {
+			case "foreground":
+				t.Foreground = swatch
+			case "background":
+				t.Background = swatch
+			case "cursor":
+				t.Cursor = swatch
+			default:
+				t.Colors = append(t.Colors, swatch)
+				continue
+			}
+
+			t.Special = append(t.Special, swatch)
+		}
+
+		data.Themes = append(data.Themes, t)
+	}
+
+	if err := htmlPage.Execute(w, data); err != nil {
+		return fmt.Errorf("unable to render the preview: %s", err.Error())
+	}
+
+	return nil
+}
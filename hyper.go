@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	reHyperColors = regexp.MustCompile(`\bcolors\s*:\s*\{`)
+	reHyperEntry  = regexp.MustCompile(`['"]?\b([A-Za-z]+)['"]?\s*:\s*(?:'([^']*)'|"([^"]*)")`)
+)
+
+// hyperSettings maps the top-level color settings of a Hyper config to the
+// Xresources keys they set.
+var hyperSettings = map[string]string{
+	"foregroundColor":   "foreground",
+	"backgroundColor":   "background",
+	"cursorColor":       "cursorColor",
+	"cursorAccentColor": "cursorColor2",
+	"selectionColor":    "highlightColor",
+}
+
+// hyperColors maps the names used in the Hyper "colors" object to the
+// palette keys, "lightBlack" through "lightWhite" being the bright ones.
+var hyperColors = map[string]string{}
+
+func init() {
+	for i, name := range ansiColorNames {
+		hyperColors[name] = fmt.Sprintf("color%d", i)
+		hyperColors["light"+strings.ToUpper(name[:1])+name[1:]] = fmt.Sprintf("color%d", i+8)
+	}
+}
+
+func detectHyper(content string) bool {
+	return strings.Contains(content, "module.exports") &&
+		(strings.Contains(content, "foregroundColor") || reHyperColors.MatchString(content))
+}
+
+// parseHyper extracts the colors from a .hyper.js config without running
+// it, reading the "name: 'value'" pairs of the top-level settings and of
+// the "colors" object line by line. Values written as "rgba(r,g,b,a)" are
+// accepted, but the alpha channel is dropped.
+func parseHyper(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+	hasColors, inColors := false, false
+
+	read := func(line sourceLine, text string, keys map[string]string) {
+		for _, m := range reHyperEntry.FindAllStringSubmatch(text, -1) {
+			key, found := keys[m[1]]
+			if !found {
+				continue
+			}
+
+			value := m[2] + m[3]
+			if v, dropped := dropAlpha(value); dropped {
+				warnf("%s: dropping the alpha channel of %s, KiTTY doesn't support transparency", line, m[1])
+				value = v
+			}
+
+			debugf("%s: using %s: %s", line, m[1], value)
+			values[key] = resource{value: value, source: line}
+		}
+	}
+
+	stripped := strings.Split(stripCComments(joinLines(lines)), "\n")
+
+	for i, line := range lines {
+		text := stripped[i]
+
+		if !inColors {
+			loc := reHyperColors.FindStringIndex(text)
+			if loc == nil {
+				read(line, text, hyperSettings)
+				continue
+			}
+
+			read(line, text[:loc[0]], hyperSettings)
+			text, hasColors, inColors = text[loc[1]:], true, true
+		}
+
+		end := strings.Index(text, "}")
+		if end < 0 {
+			read(line, text, hyperColors)
+			continue
+		}
+
+		read(line, text[:end], hyperColors)
+		read(line, text[end+1:], hyperSettings)
+		inColors = false
+	}
+
+	if !hasColors {
+		return nil, errors.New("no colors object found in Hyper configuration, expected one inside module.exports like: config: { colors: { black: '#000000', red: '#cc6666', ... } }")
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "Hyper configuration")
+	return values, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// sourceLine is a single line of an input file, keeping track of the file
+// it came from so values can be reported accurately.
+type sourceLine struct {
+	file string
+	num  int
+	text string
+}
+
+func (sl sourceLine) String() string {
+	if sl.num == 0 {
+		return sl.file
+	}
+
+	return fmt.Sprintf("%s:%d", sl.file, sl.num)
+}
+
+// resource is a single color value found in an input file, along with
+// where it was found and how specific its resource path was.
+type resource struct {
+	value       string
+	source      sourceLine
+	specificity int
+
+	// format is the name of the input format the resource was read as.
+	format string
+}
+
+// invalid builds the error returned when the resource value can't be
+// parsed as a color, quoting the whole line so invisible characters such
+// as stray carriage returns or non-breaking spaces show up.
+func (r resource) invalid(key string, err error) error {
+	if r.source.num == 0 {
+		return withCode(exitParse, fmt.Errorf("%s: unable to parse %q for key %q (from %s): %s", r.source, r.value, key, r.source.text, err.Error()))
+	}
+
+	return withCode(exitParse, fmt.Errorf("%s: unable to parse %q for key %q: %s (line: %q)", r.source, r.value, key, err.Error(), r.source.text))
+}
+
+// readInput reads one of the input files given on the command line. On top
+// of what readLines supports, dotshare.it theme pages are accepted, with
+// the color scheme extracted from the page.
+func readInput(fname string) ([]sourceLine, error) {
+	if isDotshareURL(fname) {
+		return readDotshare(fname)
+	}
+
+	return readLines(fname)
+}
+
+// readLines reads the given file, or stdin if the file name is "-", and
+// returns its lines. File names that are http or https URLs, or use the
+// "github:" shorthand, are downloaded.
+func readLines(fname string) ([]sourceLine, error) {
+	if fname == "-" {
+		return scanLines("stdin", os.Stdin)
+	}
+
+	if strings.HasPrefix(fname, builtinPrefix) {
+		return readBuiltinTheme(fname)
+	}
+
+	policy := cacheRevalidate
+	if expanded, ok := expandGitHub(fname); ok {
+		debugf("expanded %s to %s", fname, expanded)
+		fname, policy = expanded, cacheReuse
+	}
+
+	if isURL(fname) {
+		body, err := fetchURL(fname, policy)
+		if err != nil {
+			return nil, err
+		}
+
+		return scanLines(fname, bytes.NewReader(body))
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("can't open file %q: %s", fname, err.Error())
+	}
+
+	defer f.Close()
+
+	return scanLines(fname, f)
+}
+
+func scanLines(fname string, r io.Reader) ([]sourceLine, error) {
+	var b bytes.Buffer
+	if _, err := io.Copy(&b, r); err != nil {
+		return nil, fmt.Errorf("can't read %s: %s", sourceName(fname), err.Error())
+	}
+
+	var lines []sourceLine
+	scanner := bufio.NewScanner(bytes.NewReader(normalizeText(b.Bytes())))
+
+	for num := 1; scanner.Scan(); num++ {
+		lines = append(lines, sourceLine{file: fname, num: num, text: scanner.Text()})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read %s: %s", sourceName(fname), err.Error())
+	}
+
+	return lines, nil
+}
+
+// normalizeText strips a leading UTF-8 byte order mark and converts CRLF
+// and lone CR line endings to LF, so files saved on Windows or old Macs
+// parse the same way as the rest. UTF-16 text with a byte order mark, such
+// as the files exported by regedit, is converted to UTF-8.
+func normalizeText(b []byte) []byte {
+	b = decodeUTF16(b)
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(b, []byte("\r"), []byte("\n"))
+}
+
+// decodeUTF16 converts UTF-16 text starting with a byte order mark to
+// UTF-8, returning any other text unchanged.
+func decodeUTF16(b []byte) []byte {
+	var order binary.ByteOrder
+
+	switch {
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		order = binary.BigEndian
+	default:
+		return b
+	}
+
+	units := make([]uint16, 0, len(b)/2)
+	for i := 2; i+1 < len(b); i += 2 {
+		units = append(units, order.Uint16(b[i:]))
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
+
+// joinLines returns the text of the given lines, one per line.
+func joinLines(lines []sourceLine) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(line.text)
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+// mergeResources copies the values from src into dst. Values already in
+// dst are only replaced by values at least as specific, so merging files
+// behaves like "xrdb -merge".
+func mergeResources(dst, src map[string]resource) {
+	for key, res := range src {
+		if prev, found := dst[key]; found && prev.specificity > res.specificity {
+			debugf("%s: ignoring %s in favor of the more specific entry on %s", res.source, key, prev.source)
+			continue
+		}
+
+		dst[key] = res
+	}
+}
+
+// sourceName describes the input for error messages.
+func sourceName(fname string) string {
+	if fname == "-" || fname == "stdin" {
+		return "stdin"
+	}
+
+	if isURL(fname) {
+		return fmt.Sprintf("URL %q", fname)
+	}
+
+	if strings.HasPrefix(fname, builtinPrefix) {
+		return fmt.Sprintf("built-in theme %q", strings.TrimPrefix(fname, builtinPrefix))
+	}
+
+	return fmt.Sprintf("file %q", fname)
+}
+
+// sourcesName describes one or more inputs for error messages.
+func sourcesName(fnames []string) string {
+	if len(fnames) == 1 {
+		return sourceName(fnames[0])
+	}
+
+	return "input files"
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"lf", "a\nb\n", "a\nb\n"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n"},
+		{"cr", "a\rb\r", "a\nb\n"},
+		{"mixed", "a\r\nb\rc\n", "a\nb\nc\n"},
+		{"bom", "\xef\xbb\xbfa\n", "a\n"},
+		{"bom and crlf", "\xef\xbb\xbfa\r\nb\r\n", "a\nb\n"},
+		{"utf-16le", "\xff\xfea\x00\r\x00\n\x00", "a\n"},
+		{"utf-16be", "\xfe\xff\x00a\x00\n", "a\n"},
+	}
+
+	for _, tt := range tests {
+		if got := string(normalizeText([]byte(tt.in))); got != tt.want {
+			t.Errorf("%s: normalizeText(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBOMAndCRLF(t *testing.T) {
+	values := readTestValues(t, "bom-crlf.Xresources")
+
+	for key, value := range map[string]string{
+		"foreground": "#f8f8f2",
+		"color0":     "#21222c",
+		"color15":    "#ffffff",
+	} {
+		if got := values[key].value; got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if _, err := convert(values); err != nil {
+		t.Errorf("convert() = %s", err)
+	}
+}
+
+func TestInvisibleCharactersShown(t *testing.T) {
+	_, err := convert(readTestValues(t, "zero-width.Xresources"))
+	if err == nil {
+		t.Fatal("convert() succeeded, want an error for the zero-width space after color3")
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, `\u200b`) || !strings.Contains(msg, "zero-width.Xresources:12") {
+		t.Errorf("convert() = %q, want the line quoted with the zero-width space made visible", msg)
+	}
+}
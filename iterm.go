@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// plistNode is a generic XML element of a property list, keeping its
+// children in order since dictionaries are written as alternating <key>
+// and value elements.
+type plistNode struct {
+	XMLName xml.Name
+	Text    string      `xml:",chardata"`
+	Nodes   []plistNode `xml:",any"`
+}
+
+// dict returns the entries of a <dict> element.
+func (n plistNode) dict() map[string]plistNode {
+	entries := map[string]plistNode{}
+	for i := 0; i+1 < len(n.Nodes); i += 2 {
+		if n.Nodes[i].XMLName.Local == "key" {
+			entries[strings.TrimSpace(n.Nodes[i].Text)] = n.Nodes[i+1]
+		}
+	}
+
+	return entries
+}
+
+// itermColors maps the iTerm2 color names to the Xresources keys they
+// set. The "Ansi N Color" entries are added in init.
+var itermColors = map[string]string{
+	"Foreground Color":    "foreground",
+	"Background Color":    "background",
+	"Cursor Color":        "cursorColor",
+	"Cursor Text Color":   "cursorColor2",
+	"Bold Color":          "colorBD",
+	"Selection Color":     "highlightColor",
+	"Selected Text Color": "highlightTextColor",
+}
+
+func init() {
+	for i := 0; i < 16; i++ {
+		itermColors[fmt.Sprintf("Ansi %d Color", i)] = fmt.Sprintf("color%d", i)
+	}
+}
+
+func detectIterm(content string) bool {
+	return strings.Contains(content, "<plist") && strings.Contains(content, "Ansi 0 Color")
+}
+
+// parseIterm reads an iTerm2 .itermcolors property list, where each color
+// is a dictionary of Red, Green and Blue components between 0 and 1.
+func parseIterm(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	var root plistNode
+	if err := xml.Unmarshal([]byte(joinLines(lines)), &root); err != nil {
+		return nil, fmt.Errorf("invalid iTerm2 property list: %s", err.Error())
+	}
+
+	if len(root.Nodes) == 0 || root.Nodes[0].XMLName.Local != "dict" {
+		return nil, fmt.Errorf("invalid iTerm2 property list: expecting a top-level <dict>")
+	}
+
+	values := map[string]resource{}
+
+	for name, node := range root.Nodes[0].dict() {
+		key, found := itermColors[name]
+		if !found {
+			continue
+		}
+
+		components := node.dict()
+		channels := make([]uint8, 0, 3)
+
+		for _, component := range []string{"Red Component", "Green Component", "Blue Component"} {
+			v, err := strconv.ParseFloat(strings.TrimSpace(components[component].Text), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s for %q: %q is not a number", component, name, components[component].Text)
+			}
+
+			channels = append(channels, floatToChannel(v))
+		}
+
+		value := fmt.Sprintf("#%02x%02x%02x", channels[0], channels[1], channels[2])
+		debugf("%s: using %s: %s", lines[0].file, name, value)
+		values[key] = resource{value: value, source: fieldSource(lines, name)}
+	}
+
+	return values, nil
+}
+
+// floatToChannel converts a color component between 0 and 1 to 8 bits,
+// rounding halves away from zero so 0.5 becomes 128 the way iTerm2 and
+// most color pickers display it.
+func floatToChannel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// writeIterm writes the colors as an iTerm2 .itermcolors property list,
+// laid out the way iTerm2 exports them: keys sorted by name and sRGB
+// components with enough digits to get the same 8-bit values back.
+func writeIterm(w io.Writer, _ string, p palette, _ encodeOptions) error {
+	names := map[string]string{}
+	for name, key := range itermColors {
+		if _, found := p.keys[key]; found {
+			names[name] = key
+		}
+	}
+
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">`)
+	fmt.Fprintln(w, `<plist version="1.0">`)
+	fmt.Fprintln(w, "<dict>")
+
+	for _, name := range sortedKeys(names) {
+		c := p.keys[names[name]]
+		component := func(v uint8) string {
+			return strconv.FormatFloat(float64(v)/255, 'g', -1, 64)
+		}
+
+		fmt.Fprintf(w, "\t<key>%s</key>\n", name)
+		fmt.Fprintln(w, "\t<dict>")
+		fmt.Fprintln(w, "\t\t<key>Alpha Component</key>")
+		fmt.Fprintln(w, "\t\t<real>1</real>")
+		fmt.Fprintln(w, "\t\t<key>Blue Component</key>")
+		fmt.Fprintf(w, "\t\t<real>%s</real>\n", component(c.B))
+		fmt.Fprintln(w, "\t\t<key>Color Space</key>")
+		fmt.Fprintln(w, "\t\t<string>sRGB</string>")
+		fmt.Fprintln(w, "\t\t<key>Green Component</key>")
+		fmt.Fprintf(w, "\t\t<real>%s</real>\n", component(c.G))
+		fmt.Fprintln(w, "\t\t<key>Red Component</key>")
+		fmt.Fprintf(w, "\t\t<real>%s</real>\n", component(c.R))
+		fmt.Fprintln(w, "\t</dict>")
+	}
+
+	fmt.Fprintln(w, "</dict>")
+	fmt.Fprintln(w, "</plist>")
+
+	return nil
+}
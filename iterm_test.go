@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+func TestFloatToChannel(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want uint8
+	}{
+		{0, 0},
+		{1, 255},
+		{0.5, 128},
+		{127.5 / 255, 128},
+		{126.5 / 255, 127},
+		{0.4999 / 255, 0},
+		{0.5 / 255, 1},
+		{0.11372549019607843, 0x1d},
+		{0.9999999, 255},
+		{-0.1, 0},
+		{1.2, 255},
+	}
+
+	for _, tt := range tests {
+		if got := floatToChannel(tt.v); got != tt.want {
+			t.Errorf("floatToChannel(%v) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestParseIterm(t *testing.T) {
+	values := readTestValues(t, "tomorrow-night.itermcolors")
+
+	for key, value := range map[string]string{
+		"foreground":         "#c5c8c6",
+		"background":         "#1d1f21",
+		"cursorColor":        "#c5c8c6",
+		"cursorColor2":       "#1d1f21",
+		"colorBD":            "#ffffff",
+		"color0":             "#1d1f21",
+		"color1":             "#cc6666",
+		"color15":            "#ffffff",
+		"highlightColor":     "#803300",
+		"highlightTextColor": "#c5c8c6",
+	} {
+		if got := values[key].value; got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if _, err := convert(values); err != nil {
+		t.Errorf("convert() = %s", err)
+	}
+}
+
+func TestWriteIterm(t *testing.T) {
+	p := builtinTestPalette(t, "dracula")
+
+	var b bytes.Buffer
+	if err := writeIterm(&b, "Dracula", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "dracula.itermcolors", b.Bytes())
+
+	dec := xml.NewDecoder(bytes.NewReader(b.Bytes()))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("the plist isn't well formed: %s", err)
+			}
+
+			break
+		}
+	}
+
+	// the components have to read back as the same channels
+	lines, err := scanLines("dracula.itermcolors", bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := parseIterm(lines, decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, c := range p.keys {
+		if got.keys[key] != c {
+			t.Errorf("%s read back as %v, want %v", key, got.keys[key], c)
+		}
+	}
+}
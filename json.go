@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonTheme is the resolved palette as written by the json output
+// format. The optional colors are left out when the input has none.
+type jsonTheme struct {
+	Foreground          string          `json:"foreground"`
+	Background          string          `json:"background"`
+	Cursor              string          `json:"cursor"`
+	CursorText          string          `json:"cursorText,omitempty"`
+	SelectionForeground string          `json:"selectionForeground,omitempty"`
+	SelectionBackground string          `json:"selectionBackground,omitempty"`
+	Colors              []string        `json:"colors"`
+	Source              []paletteSource `json:"source"`
+}
+
+// writeJSON writes the colors as a JSON object, meant to be processed by
+// other tools. Colors are always lowercase "#rrggbb", and source lists the
+// input files the colors were read from along with their format.
+func writeJSON(w io.Writer, _ string, p palette, _ encodeOptions) error {
+	optional := func(key string) string {
+		if c, found := p.keys[key]; found {
+			return hexColor(c)
+		}
+
+		return ""
+	}
+
+	theme := jsonTheme{
+		Foreground:          hexColor(p.keys["foreground"]),
+		Background:          hexColor(p.keys["background"]),
+		Cursor:              hexColor(p.keys["cursorColor"]),
+		CursorText:          optional("cursorColor2"),
+		SelectionForeground: optional("highlightTextColor"),
+		SelectionBackground: optional("highlightColor"),
+		Source:              p.sources,
+	}
+
+	for _, key := range paletteKeys() {
+		theme.Colors = append(theme.Colors, hexColor(p.keys[key]))
+	}
+
+	out, err := json.MarshalIndent(theme, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode the theme: %s", err.Error())
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
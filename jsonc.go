@@ -0,0 +1,93 @@
+package main
+
+import "bytes"
+
+// stripJSONComments turns JSON with comments, as used by the settings
+// files of Windows Terminal and VS Code, into plain JSON, removing "//"
+// and "/* */" comments outside of strings along with trailing commas
+// before a closing bracket or brace.
+func stripJSONComments(src []byte) []byte {
+	var (
+		out      bytes.Buffer
+		inString bool
+	)
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				out.WriteByte(src[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				// an unclosed comment runs to the end of the file
+				i = len(src)
+				break
+			}
+
+			i += end + 3
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return removeTrailingCommas(out.Bytes())
+}
+
+// removeTrailingCommas drops commas followed only by whitespace and a
+// closing bracket or brace, outside of strings.
+func removeTrailingCommas(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	inString := false
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				out = append(out, src[i])
+			} else if c == '"' {
+				inString = false
+			}
+
+			continue
+		}
+
+		if c == '"' {
+			inString = true
+		}
+
+		if c == ',' {
+			j := i + 1
+			for j < len(src) && (src[j] == ' ' || src[j] == '\t' || src[j] == '\n' || src[j] == '\r') {
+				j++
+			}
+
+			if j < len(src) && (src[j] == '}' || src[j] == ']') {
+				continue
+			}
+		}
+
+		out = append(out, c)
+	}
+
+	return out
+}
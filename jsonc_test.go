@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestStripJSONComments(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{"a": 1, // one` + "\n" + `}`, `{"a": 1 ` + "\n" + `}`},
+		{`{"a": "http://x/*y*/", /* b */ "c": [1, 2,],}`, `{"a": "http://x/*y*/",  "c": [1, 2]}`},
+		{`{"a": "\"//"}`, `{"a": "\"//"}`},
+		{`{"a": [1, 2,],} /* left open`, `{"a": [1, 2]} `},
+	}
+
+	for _, tt := range tests {
+		if got := string(stripJSONComments([]byte(tt.src))); got != tt.want {
+			t.Errorf("stripJSONComments(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reKittyConfLine   = regexp.MustCompile(`^[ \t]*([a-z0-9_]+)[ \t]+(\S+)`)
+	reKittyConfDetect = regexp.MustCompile(`(?m)^[ \t]*(color[0-9]+|foreground|background)[ \t]+#[0-9a-fA-F]`)
+)
+
+// kittyConfSettings maps the settings of the kitty terminal to the
+// Xresources keys they set. The palette colors are handled separately.
+var kittyConfSettings = map[string]string{
+	"foreground":           "foreground",
+	"background":           "background",
+	"cursor":               "cursorColor",
+	"cursor_text_color":    "cursorColor2",
+	"selection_background": "highlightColor",
+	"selection_foreground": "highlightTextColor",
+}
+
+func detectKittyConf(content string) bool {
+	return reKittyConfDetect.MatchString(content)
+}
+
+// parseKittyConf reads a theme for the kitty terminal, made of
+// "name value" lines. Palette entries above color15 and settings that
+// aren't colors are skipped.
+func parseKittyConf(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line.text), "#") {
+			continue
+		}
+
+		m := reKittyConfLine.FindStringSubmatch(line.text)
+		if m == nil {
+			continue
+		}
+
+		key, found := kittyConfSettings[m[1]]
+		if !found {
+			n, err := strconv.Atoi(strings.TrimPrefix(m[1], "color"))
+			if !strings.HasPrefix(m[1], "color") || err != nil || n > 15 {
+				continue
+			}
+
+			key = "color" + strconv.Itoa(n)
+		}
+
+		// kitty allows some of these to follow the cell colors, which
+		// KiTTY can't represent
+		if m[2] == "none" || m[2] == "background" || m[2] == "foreground" {
+			continue
+		}
+
+		debugf("%s: using %s: %s", line, m[1], m[2])
+		values[key] = resource{value: m[2], source: line}
+	}
+
+	return values, nil
+}
+
+// writeKittyConf writes the colors as settings for the kitty terminal,
+// meant to be pulled into kitty.conf with "include". The session name is
+// only used in the header comment.
+func writeKittyConf(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	names := make(map[string]string, len(kittyConfSettings))
+	for name, key := range kittyConfSettings {
+		names[key] = name
+	}
+
+	keys := append([]string{"foreground", "background", "cursorColor", "cursorColor2", "highlightColor", "highlightTextColor"}, paletteKeys()...)
+
+	fmt.Fprintf(w, "# %s\n", sname)
+
+	for _, key := range keys {
+		c, found := p.keys[key]
+		if !found {
+			continue
+		}
+
+		name := key
+		if n, renamed := names[key]; renamed {
+			name = n
+		}
+
+		fmt.Fprintf(w, "%s %s\n", name, hexColor(c))
+	}
+
+	return nil
+}
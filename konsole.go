@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reKonsoleSection = regexp.MustCompile(`^Color([0-7])(Intense)?$`)
+
+func detectKonsole(content string) bool {
+	return strings.Contains(content, "[Color0]") && strings.Contains(content, "[Background]")
+}
+
+// parseKonsole reads a KDE Konsole .colorscheme file, where each color is
+// its own INI section with a decimal "Color=r,g,b" value. ColorN maps to
+// colorN and ColorNIntense to colorN+8. Konsole has no cursor color, so
+// the foreground is used instead.
+func parseKonsole(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+
+	for _, e := range parseINI(lines) {
+		if e.key != "Color" {
+			continue
+		}
+
+		var key string
+		switch {
+		case e.section == "Foreground":
+			key = "foreground"
+		case e.section == "Background":
+			key = "background"
+		default:
+			m := reKonsoleSection.FindStringSubmatch(e.section)
+			if m == nil {
+				continue
+			}
+
+			n, _ := strconv.Atoi(m[1])
+			if m[2] != "" {
+				n += 8
+			}
+
+			key = fmt.Sprintf("color%d", n)
+		}
+
+		c, err := parseColor(e.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid color in section [%s]: %s", e.source, e.section, err.Error())
+		}
+
+		debugf("%s: using [%s] %s: %s", e.source, e.section, e.key, e.value)
+		values[key] = resource{value: hexColor(c), source: e.source}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "Konsole color scheme")
+	return values, nil
+}
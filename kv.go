@@ -0,0 +1,215 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	reINISection = regexp.MustCompile(`^[ \t]*(\[+)[ \t]*([^\]]*?)[ \t]*\]+[ \t]*$`)
+	reINIEntry   = regexp.MustCompile(`^[ \t]*([^=\s]+(?:[ \t]+[^=\s]+)*)[ \t]*=[ \t]*(.*)$`)
+	reYAMLEntry  = regexp.MustCompile(`^([ \t]*)(?:-[ \t]+)?["']?([^"':#]+?)["']?[ \t]*:(?:[ \t]+(.*))?$`)
+)
+
+// kvEntry is a single "key = value" setting from an INI, TOML or YAML
+// file, along with the section it belongs to. Nested sections are joined
+// with dots, so the YAML "colors: primary: background:" path and the TOML
+// "[colors.primary]" section both end up as "colors.primary".
+type kvEntry struct {
+	section string
+	key     string
+	value   string
+	depth   int
+	source  sourceLine
+}
+
+func (e kvEntry) path() string {
+	if e.section == "" {
+		return e.key
+	}
+
+	return e.section + "." + e.key
+}
+
+func (e kvEntry) resource() resource {
+	return resource{value: e.value, source: e.source}
+}
+
+// parseINI reads the entries of INI-like files, including TOML, where
+// sections are written as "[name]" or "[[name]]" (the depth being the
+// number of brackets) and settings as "key = value". Lines starting with
+// "#" or ";" are comments, and surrounding quotes are removed from values.
+func parseINI(lines []sourceLine) []kvEntry {
+	var (
+		entries []kvEntry
+		section string
+		depth   int
+	)
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		trimmed := strings.TrimSpace(line.text)
+		if trimmed == "" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			tracef("%s: skipping, it's a comment", line)
+			continue
+		}
+
+		if m := reINISection.FindStringSubmatch(line.text); m != nil {
+			section, depth = m[2], len(m[1])
+			continue
+		}
+
+		m := reINIEntry.FindStringSubmatch(line.text)
+		if m == nil {
+			tracef("%s: skipping, it isn't a \"key = value\" line", line)
+			continue
+		}
+
+		value := strings.TrimSpace(m[2])
+
+		// TOML arrays are kept as written, and can span several lines
+		if strings.HasPrefix(value, "[") {
+			for !strings.Contains(stripTOMLComment(value), "]") && i+1 < len(lines) {
+				i++
+				value += " " + stripTOMLComment(strings.TrimSpace(lines[i].text))
+			}
+		} else {
+			value = unquoteValue(value)
+		}
+
+		entries = append(entries, kvEntry{
+			section: section,
+			key:     m[1],
+			value:   value,
+			depth:   depth,
+			source:  line,
+		})
+	}
+
+	return entries
+}
+
+// parseYAML reads the "key: value" mappings of simple YAML files, using
+// indentation to track nesting. It's far from a complete YAML parser, but
+// it's enough for the flat color maps terminal themes are written in.
+func parseYAML(lines []sourceLine) []kvEntry {
+	type level struct {
+		indent int
+		key    string
+	}
+
+	var (
+		entries []kvEntry
+		stack   []level
+	)
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line.text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		m := reYAMLEntry.FindStringSubmatch(line.text)
+		if m == nil {
+			continue
+		}
+
+		indent := len(m[1])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		value := unquoteValue(m[3])
+		if value == "" {
+			stack = append(stack, level{indent: indent, key: m[2]})
+			continue
+		}
+
+		keys := make([]string, 0, len(stack))
+		for _, l := range stack {
+			keys = append(keys, l.key)
+		}
+
+		entries = append(entries, kvEntry{
+			section: strings.Join(keys, "."),
+			key:     m[2],
+			value:   value,
+			depth:   len(stack),
+			source:  line,
+		})
+	}
+
+	return entries
+}
+
+// unquoteValue removes the quotes around a value, or, for unquoted
+// values, a trailing comment introduced by whitespace and "#" or ";".
+// "#rrggbb" colors are left alone since the "#" starts the value.
+func unquoteValue(s string) string {
+	s = strings.TrimSpace(s)
+
+	if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+		if end := strings.IndexByte(s[1:], s[0]); end >= 0 {
+			return s[1 : end+1]
+		}
+	}
+
+	for _, marker := range []string{" #", "\t#", " ;", "\t;"} {
+		if pos := strings.Index(s, marker); pos >= 0 {
+			s = s[:pos]
+		}
+	}
+
+	return strings.TrimRight(strings.TrimSpace(s), ",")
+}
+
+// parseTOMLArray returns the items of a TOML array of strings, such as
+// ["#1d1f21", "#cc6666"], with their quotes removed.
+func parseTOMLArray(s string) []string {
+	s = strings.TrimSpace(stripTOMLComment(s))
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = unquoteValue(stripTOMLComment(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// stripTOMLComment removes a "#" comment from a line of a TOML array,
+// ignoring "#" characters inside quoted strings.
+func stripTOMLComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return strings.TrimSpace(s[:i])
+		}
+	}
+
+	return s
+}
+
+// kvLookup indexes entries by their full path, later entries winning.
+func kvLookup(entries []kvEntry) map[string]kvEntry {
+	lookup := make(map[string]kvEntry, len(entries))
+	for _, e := range entries {
+		lookup[e.path()] = e
+	}
+
+	return lookup
+}
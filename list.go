@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// listEntry is a row of the list output: a key the input can set, and how
+// it was read, if at all.
+type listEntry struct {
+	Key      string `json:"key"`
+	Required bool   `json:"required"`
+	Present  bool   `json:"present"`
+	Value    string `json:"value,omitempty"`
+	Hex      string `json:"hex,omitempty"`
+	RGB      []int  `json:"rgb,omitempty"`
+	Colours  []int  `json:"colours"`
+	Source   string `json:"source,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// listKeys returns every key the input can set, in the order they're
+// listed: the special colors, the palette, then the keys KiTTY has no
+// Colour setting for.
+func listKeys() []string {
+	keys := []string{"foreground", "background", "cursorColor", "cursorColor2", "colorBD"}
+	keys = append(keys, paletteKeys()...)
+	keys = append(keys, ignoredKeys...)
+	return append(keys, unsupportedKeys...)
+}
+
+// listEntries describes each key for the list output. Missing and invalid
+// keys are marked as such rather than failing, as convert would.
+func listEntries(values map[string]resource) []listEntry {
+	keys := listKeys()
+	entries := make([]listEntry, 0, len(keys))
+
+	for _, key := range keys {
+		slots, required := nameReplacements[key]
+		if !required {
+			slots = optionalReplacements[key]
+		}
+
+		e := listEntry{Key: key, Required: required, Colours: append([]int{}, slots...)}
+
+		if res, found := values[key]; found {
+			e.Present, e.Value, e.Source = true, res.value, res.source.String()
+
+			if c, err := parseColor(res.value); err != nil {
+				e.Error = err.Error()
+			} else {
+				e.Hex, e.RGB = hexColor(c), []int{int(c.R), int(c.G), int(c.B)}
+			}
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+// writeList prints the keys read from the input as a table, with a swatch
+// of each color when swatches is set, or as JSON.
+func writeList(w io.Writer, values map[string]resource, asJSON, swatches bool) error {
+	entries := listEntries(values)
+
+	if asJSON {
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to encode the palette: %s", err.Error())
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", out)
+		return err
+	}
+
+	// the swatches are put in front of the table, as their escape
+	// sequences would throw off its alignment
+	var table bytes.Buffer
+	swatch := []string{"     "}
+
+	tw := tabwriter.NewWriter(&table, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tHEX\tRGB\tKITTY\tSOURCE")
+
+	for _, e := range entries {
+		cell, hex, rgb := "     ", "-", "-"
+		switch {
+		case !e.Present && e.Required:
+			hex = "missing"
+		case !e.Present:
+			hex = "absent"
+		case e.Error != "":
+			hex = "invalid"
+		default:
+			hex, rgb = e.Hex, fmt.Sprintf("%d,%d,%d", e.RGB[0], e.RGB[1], e.RGB[2])
+			cell = fmt.Sprintf("\033[48;2;%d;%d;%dm    \033[0m ", e.RGB[0], e.RGB[1], e.RGB[2])
+		}
+
+		swatch = append(swatch, cell)
+
+		kitty := "-"
+		if len(e.Colours) > 0 {
+			names := make([]string, 0, len(e.Colours))
+			for _, n := range e.Colours {
+				names = append(names, fmt.Sprintf("%s%d", colorPrefix, n))
+			}
+
+			kitty = strings.Join(names, ", ")
+		}
+
+		source := e.Source
+		switch {
+		case source == "":
+			source = "-"
+		case e.Error != "":
+			source += fmt.Sprintf(", %q: %s", e.Value, e.Error)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Key, hex, rgb, kitty, source)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(table.String(), "\n")
+	for i, line := range lines[:len(lines)-1] {
+		if swatches {
+			line = swatch[i] + line
+		}
+
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isTruecolor reports whether the terminal says it shows 24-bit colors.
+func isTruecolor() bool {
+	colorterm := os.Getenv("COLORTERM")
+	return (colorterm == "truecolor" || colorterm == "24bit") && os.Getenv("NO_COLOR") == ""
+}
+
+// runList prints the colors read from the input, without converting
+// them, to check what the input is read as.
+func runList(args []string) error {
+	fs := newFlagSet("list")
+	in := addInputFlags(fs)
+	asJSON := fs.Bool("json", false, "print the palette as JSON")
+	addFlagAliases(fs)
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printCommandUsage(w, "list", fs) })
+	if err != nil {
+		return err
+	}
+
+	fnames, err := in.files(args)
+	if err != nil {
+		return err
+	}
+
+	values, _, err := in.readValues(fnames)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		return withCode(exitParse, fmt.Errorf("%s format is invalid: no color codes found", sourcesName(fnames)))
+	}
+
+	return listValues(os.Stdout, values, *asJSON)
+}
+
+// listValues writes the list output to stdout, with swatches when it's a
+// terminal showing 24-bit colors.
+func listValues(w *os.File, values map[string]resource, asJSON bool) error {
+	if err := writeList(w, values, asJSON, isTerminal(w) && isTruecolor()); err != nil {
+		return withCode(exitOutput, err)
+	}
+
+	return nil
+}
@@ -1,22 +1,28 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"image/color"
 	"io"
-	"net/url"
+	"log/slog"
 	"os"
-	"regexp"
+	"os/signal"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
-var reParseItems = regexp.MustCompile(`\*\.(color[0-9]{1,2}|foreground|background|cursorColor)+: +(#[a-fA-F0-9]{6})`)
-
 const colorPrefix = "Colour"
 
+var errUsage = errors.New("usage: urxvt-kitty [flags] -i <file> [-s <session>], or urxvt-kitty [flags] <file>... [<session>] -- run \"urxvt-kitty --help\" for the flags")
+
 var nameReplacements = map[string][]int{
 	"foreground":  {0, 1},
 	"background":  {2, 3},
@@ -39,8 +45,30 @@ var nameReplacements = map[string][]int{
 	"color15":     {21},
 }
 
+// optionalReplacements are keys that don't need to be present in the
+// input, but when they are, they override the slots set by the keys in
+// nameReplacements.
+var optionalReplacements = map[string][]int{
+	"cursorColor2": {5},
+	"colorBD":      {1},
+}
+
+// ignoredKeys are parsed from the input but have no KiTTY equivalent, so
+// they are only reported when running with --verbose.
+var ignoredKeys = []string{"colorIT", "colorUL"}
+
+// unsupportedKeys are the selection colors, parsed from the input but
+// with no place in a KiTTY or PuTTY session: Colour0 to Colour21 are the
+// only colors a session has, and both show the selection in reverse video
+// instead. A warning is printed when they are dropped from one, while the
+// formats of terminals with selection colors, like kitty-conf, write them.
+var unsupportedKeys = []string{"highlightColor", "highlightTextColor"}
+
+// colormatch is a KiTTY session color, along with the number of its
+// Colour setting, which it is sorted by.
 type colormatch struct {
 	name  string
+	index int
 	color color.RGBA
 }
 
@@ -48,131 +76,1066 @@ func (cm *colormatch) getRGB() string {
 	return fmt.Sprintf("%d,%d,%d", cm.color.R, cm.color.G, cm.color.B)
 }
 
+// palette is the converted input: the KiTTY session colors, in the
+// numeric order regedit shows them in, the color of each key they were
+// set from, which the output formats for other terminals use instead, and
+// the input files the colors were read from.
+type palette struct {
+	slots   []colormatch
+	keys    map[string]color.RGBA
+	sources []paletteSource
+}
+
+// namedPalette is a converted theme along with its session name.
+type namedPalette struct {
+	name    string
+	palette palette
+}
+
+// paletteSource is an input file the palette colors were read from.
+type paletteSource struct {
+	File   string `json:"file"`
+	Format string `json:"format"`
+}
+
+// verbosity is how much --verbose prints: 1 for how the input was read and
+// the output written, and 2, with -vv or --verbose given twice, adding a
+// trace of every input line skipped and why.
+type verbosity int
+
+var verbose verbosity
+
+// quiet silences everything printed to stderr, errors included, leaving
+// only the exit status to tell how the run went.
+var quiet bool
+
+// logLevel is the level logger prints from, kept above all the levels
+// used until --verbose is given.
+var logLevel = func() *slog.LevelVar {
+	l := new(slog.LevelVar)
+	l.Set(slog.LevelError + 1)
+	return l
+}()
+
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+	Level: logLevel,
+	ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		// the time of each line is of no use for a run this short
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+
+		return a
+	},
+}))
+
+func (v *verbosity) String() string {
+	if v == nil {
+		return "0"
+	}
+
+	return strconv.Itoa(int(*v))
+}
+
+// Set raises the verbosity by one each time the flag is given, and turns
+// it off with --verbose=false. --verbose=2 sets it to 2 right away.
+func (v *verbosity) Set(s string) error {
+	if n, err := strconv.Atoi(s); err == nil && n > 1 {
+		v.set(verbosity(n))
+		return nil
+	}
+
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	if on {
+		v.set(*v + 1)
+	} else {
+		v.set(0)
+	}
+
+	return nil
+}
+
+func (v *verbosity) IsBoolFlag() bool { return true }
+
+func (v *verbosity) set(level verbosity) {
+	*v = min(level, 2)
+
+	switch *v {
+	case 0:
+		logLevel.Set(slog.LevelError + 1)
+	case 1:
+		logLevel.Set(slog.LevelInfo)
+	default:
+		logLevel.Set(slog.LevelDebug)
+	}
+}
+
+// verbosityFlag is a flag setting the verbosity to a given level, as -vv
+// does.
+type verbosityFlag struct {
+	v     *verbosity
+	level verbosity
+}
+
+func (f verbosityFlag) String() string { return "false" }
+
+func (f verbosityFlag) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	if on {
+		f.v.set(max(*f.v, f.level))
+	}
+
+	return nil
+}
+
+func (f verbosityFlag) IsBoolFlag() bool { return true }
+
+// warnf prints a warning to stderr.
+func warnf(format string, args ...interface{}) {
+	if quiet {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// notef prints an informational note to stderr.
+func notef(format string, args ...interface{}) {
+	if quiet {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Note: "+format+"\n", args...)
+}
+
+// debugf prints diagnostic messages to stderr when running with --verbose.
+func debugf(format string, args ...interface{}) {
+	if verbose > 0 && !quiet {
+		logger.Info(fmt.Sprintf(format, args...))
+	}
+}
+
+// tracef prints the finer grained messages of -vv, such as every input
+// line skipped, to stderr.
+func tracef(format string, args ...interface{}) {
+	if verbose > 1 && !quiet {
+		logger.Debug(fmt.Sprintf(format, args...))
+	}
+}
+
+// errSessionDiffers is returned by --diff-registry when the session in
+// the registry has other colors than the theme.
+var errSessionDiffers = errors.New("the session colors differ from the theme")
+
 func main() {
-	if err := app(); err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err.Error())
+	err := app()
+	switch {
+	case err == nil:
+	case errors.Is(err, errSessionDiffers):
 		os.Exit(1)
+	case errors.Is(err, flag.ErrHelp):
+	default:
+		printError(err)
+		os.Exit(exitStatus(err))
+	}
+}
+
+// printError reports the error to stderr, unless running with --quiet.
+func printError(err error) {
+	if !quiet {
+		fmt.Fprintln(os.Stderr, "Error:", err.Error())
 	}
 }
 
 func app() error {
-	switch len(os.Args[1:]) {
-	case 2:
-		// do nothing, we'll handle below
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fetchOpts.ctx = ctx
+
+	return run(os.Args[1:])
+}
+
+// runConvert converts the theme into the output format. It's the default
+// command, also run when the arguments don't start with a command name.
+func runConvert(args []string) error {
+	fs := newFlagSet("convert")
+	in := addInputFlags(fs)
+
+	to := fs.String("to", "kitty", "output format, one of: "+strings.Join(outputFormatNames(), ", "))
+
+	crawl := fs.String("crawl", "", "dotshare.it listing page to convert every theme from, following its pages")
+	outDir := fs.String("out-dir", ".", "directory to write the converted files to, always used with --crawl or --all, and instead of stdout otherwise")
+	outFile := fs.String("output", "", "file to write the converted theme to, instead of stdout")
+	force := fs.Bool("force", false, "replace the --output file when it already exists")
+	backup := fs.Bool("backup", false, "save the previous contents of the file being replaced as a .bak file next to it")
+	encoding := fs.String("encoding", "", "encoding of .reg files, utf8 or utf16 as regedit writes them (utf16 when --output names a .reg file, utf8 otherwise, and with --merge, the encoding of the merged file)")
+	all := fs.Bool("all", false, "convert every theme in the input archive, writing them to --out-dir")
+	combined := fs.String("combined", "", "with a directory or glob input, --all or --crawl, write all the themes into this single file instead of one file each in --out-dir")
+	nameTmpl := fs.String("name-template", defaultNameTemplate, "with a directory or glob input, session `name` made of {basename}, {dir}, {ext} and {index}, with modifiers as in {basename|lower|dash}")
+	strict := fs.Bool("strict", false, "with a directory or glob input, fail when any of the files fails to convert, not only when all of them do")
+
+	shell := fs.Bool("shell", false, "write the osc output as printf commands, safe to keep in a shell script")
+	author := fs.String("author", "", "author written to the output formats that store one, like --to base16 or terminalsexy")
+	cssPrefix := fs.String("css-prefix", "term-", "prefix of the custom property names written by --to css, after the leading \"--\"")
+	cssClass := fs.String("css-class", "", "with --to css, set the properties on this class instead of :root")
+	pngScale := fs.Int("png-scale", 1, "with --to png, how many times to scale up the image")
+	preview := fs.Bool("preview", false, "print a preview of the theme, to stdout when not converting it, and to stderr otherwise")
+	boldAsColour := fs.Bool("bold-as-colour", true, "with the PuTTY and KiTTY formats, set whether bold text uses the bright colors, or a bold font when false")
+	forcePalette := fs.Bool("force-palette", false, "with the PuTTY and KiTTY formats, also turn off the settings that would make the session ignore its palette")
+	templateFile := fs.String("template", "", "TOML or JSON file with more PuTTY and KiTTY session settings, like the font, to write after the colors")
+	defaultSettings := fs.Bool("default-settings", false, "write the \""+defaultSession+"\" session every new PuTTY or KiTTY session starts from, in place of the session name")
+	yes := fs.Bool("yes", false, "with --write-registry, don't ask before changing "+defaultSession)
+	merge := fs.String("merge", "", "existing .reg export to set the session colors in, keeping all its other values")
+	inPlace := fs.Bool("in-place", false, "with --merge, write the result back to the merged file")
+	registryRoot := fs.String("registry-root", "hkcu", "with the PuTTY and KiTTY .reg formats, root key to write the sessions under, hkcu or hklm")
+	registryPath := fs.String("registry-path", "", `with the PuTTY and KiTTY .reg formats, key to write the sessions under, relative to --registry-root (Software\9bis.com\KiTTY\Sessions, or Software\SimonTatham\PuTTY\Sessions with --to putty)`)
+	clean := fs.Bool("clean", false, "with the PuTTY and KiTTY .reg formats, delete each session before writing it, which drops all its other settings too")
+	deleteEverything := fs.Bool("i-know-this-deletes-everything", false, "allow --clean without --template, leaving the sessions with only their colors")
+	writeReg := fs.Bool("write-registry", false, "on Windows, set the session colors straight into the registry instead of writing a .reg file")
+	diffReg := fs.Bool("diff-registry", false, "on Windows, print how the session colors in the registry differ from the theme instead of writing them, exiting with 1 when they do and 2 on errors")
+	create := fs.Bool("create", false, "with --write-registry, create the session when it doesn't exist")
+	apply := fs.Bool("apply", false, "also recolor the current terminal with the theme, writing to its tty")
+	previewSeconds := fs.Int("preview-seconds", 0, "with --apply, put back the colors the terminal had after this many seconds, or on Ctrl-C")
+	watch := fs.Bool("watch", false, "convert the input again each time it, or a file it includes, changes, until Ctrl-C")
+
+	showVersion := fs.Bool("version", false, "print the version, git commit, build date and Go version, and exit")
+	asJSON := fs.Bool("json", false, "with --version or --list, print it as JSON")
+	list := fs.Bool("list", false, "print the colors read from the input as a table instead of converting them, marking the missing ones")
+
+	listThemes := fs.Bool("list-themes", false, "list the built-in themes and exit")
+
+	var sessions stringList
+	fs.Var(&sessions, "session", "session `name`, in place of the one after the input files; with --to kitty or putty, can be repeated to write several sessions into a single .reg file")
+	sessionsFile := fs.String("sessions-file", "", "file with the names of the sessions to write, one per line, like repeating --session")
+
+	addFlagAliases(fs)
+
+	args, err := parseFlags(fs, args, func(w io.Writer) { printUsage(w, fs) })
+	if err != nil {
+		return err
+	}
+
+	from, theme, member, fnames := in.from, in.theme, in.member, in.fnames
+
+	if *diffReg {
+		diffing = true
+	}
+
+	if *asJSON && !*showVersion && !*list {
+		return errors.New("--json only applies to --version or --list")
+	}
+
+	if *showVersion {
+		return writeVersion(os.Stdout, *asJSON)
+	}
+
+	ctx := fetchOpts.ctx
+	opts := in.decodeOptions()
+
+	output, err := findOutputFormat(*to)
+	if err != nil {
+		return err
+	}
+
+	if *shell && output.name != "osc" {
+		return errors.New("--shell only applies to --to osc")
+	}
+
+	if (flagGiven(fs, "css-prefix") || *cssClass != "") && output.name != "css" {
+		return errors.New("--css-prefix and --css-class only apply to --to css")
+	}
+
+	if flagGiven(fs, "png-scale") && output.name != "png" {
+		return errors.New("--png-scale only applies to --to png")
+	}
+
+	if *pngScale < 1 || *pngScale > 16 {
+		return errors.New("--png-scale must be between 1 and 16")
+	}
+
+	if *outFile != "" && flagGiven(fs, "out-dir") {
+		return errors.New("--output and --out-dir can't be combined, --output names the file to write and --out-dir the directory")
+	}
+
+	switch {
+	case *combined != "" && !canCombine(output):
+		return fmt.Errorf("--combined doesn't work with --to %s, which can only hold a single theme", output.name)
+	case *combined != "" && (*outFile != "" || flagGiven(fs, "out-dir")):
+		return errors.New("--combined names the single file to write, it can't be used together with --output or --out-dir")
+	}
+
+	switch *encoding {
+	case "":
+		*encoding = "utf8"
+		if output.ext == ".reg" && (strings.EqualFold(filepath.Ext(*outFile), ".reg") || strings.EqualFold(filepath.Ext(*combined), ".reg")) {
+			*encoding = "utf16"
+		}
+	case "utf8", "utf16":
+		if output.ext != ".reg" {
+			return fmt.Errorf("--encoding only applies to the .reg output formats, not %s", output.name)
+		}
 	default:
-		return errors.New("usage: urxvt-kitty [filename] [sessionName] -- get colors from: http://dotshare.it/category/terms/colors/")
+		return fmt.Errorf("unknown encoding %q, pick utf8 or utf16", *encoding)
+	}
+
+	var settings []sessionSetting
+	if *forcePalette || flagGiven(fs, "bold-as-colour") {
+		if sessionKeys[output.name] == "" && output.name != "kitty-portable" {
+			return errors.New("--bold-as-colour and --force-palette only apply to the PuTTY and KiTTY formats")
+		}
+
+		settings = paletteSettings(*forcePalette, *boldAsColour)
+	}
+
+	var tmpl *sessionTemplate
+	if *templateFile != "" {
+		if !output.isSessionFormat() {
+			return errors.New("--template only applies to the PuTTY and KiTTY formats")
+		}
+
+		var err error
+		if tmpl, err = readSessionTemplate(*templateFile); err != nil {
+			return err
+		}
+
+		for _, setting := range settings {
+			if tmpl.has(setting.name) {
+				return fmt.Errorf("the session template sets %s, which is already set by --bold-as-colour or --force-palette", setting.name)
+			}
+		}
+	}
+
+	root, found := registryRoots[strings.ToLower(*registryRoot)]
+	switch {
+	case (flagGiven(fs, "registry-root") || flagGiven(fs, "registry-path")) && sessionKeys[output.name] == "":
+		return errors.New("--registry-root and --registry-path only work with --to kitty or --to putty")
+	case !found:
+		return fmt.Errorf("unknown registry root %q, pick hkcu or hklm", *registryRoot)
+	case *registryPath == "":
+		*registryPath = sessionKeys[output.name]
+	}
+
+	if sessionKeys[output.name] != "" {
+		if err := validateRegistryPath(*registryPath); err != nil {
+			return err
+		}
+	}
+
+	output.opts = encodeOptions{settings: settings, template: tmpl, regRoot: root, regPath: *registryPath, clean: *clean, utf16: *encoding == "utf16", author: *author, shell: *shell, cssPrefix: *cssPrefix, cssClass: *cssClass, pngScale: *pngScale}
+	if err := checkCSSOptions(output.opts); err != nil {
+		return err
+	}
+
+	switch {
+	case *diffReg && !canWriteRegistry:
+		return errors.New("--diff-registry is only supported on Windows")
+	case *diffReg && sessionKeys[output.name] == "":
+		return errors.New("--diff-registry only works with --to kitty or --to putty")
+	case *diffReg && (*crawl != "" || *all || *outFile != "" || *merge != "" || *writeReg || *clean):
+		return errors.New("--diff-registry only reads a single session, it can't be combined with --crawl, --all, --output, --merge, --write-registry or --clean")
+	case *writeReg && !canWriteRegistry:
+		return errors.New("--write-registry is only supported on Windows, write a .reg file and import it there instead")
+	case *writeReg && sessionKeys[output.name] == "":
+		return errors.New("--write-registry only works with --to kitty or --to putty")
+	case *writeReg && (*crawl != "" || *all || *outFile != ""):
+		return errors.New("--write-registry writes a single session, it can't be combined with --crawl, --all or --output")
+	case *merge != "" && sessionKeys[output.name] == "":
+		return errors.New("--merge only works with --to kitty or --to putty")
+	case *merge != "" && (*crawl != "" || *all || *writeReg):
+		return errors.New("--merge writes a single session, it can't be combined with --crawl, --all or --write-registry")
+	case *clean && sessionKeys[output.name] == "":
+		return errors.New("--clean only works with --to kitty or --to putty")
+	case *clean && (*merge != "" || *writeReg):
+		return errors.New("--clean only applies to writing a new .reg file, it can't be combined with --merge or --write-registry")
+	case *clean && *templateFile == "" && !*deleteEverything:
+		return errors.New("--clean deletes every setting of the sessions, like their host names, pass --template to write them back or --i-know-this-deletes-everything to go ahead")
+	case *deleteEverything && !*clean:
+		return errors.New("--i-know-this-deletes-everything only applies to --clean")
+	case *inPlace && *merge == "":
+		return errors.New("--in-place only applies to --merge")
+	case *inPlace && (*outFile != "" || flagGiven(fs, "out-dir")):
+		return errors.New("--in-place writes back to the merged file, it can't be combined with --output or --out-dir")
+	case *force && *outFile == "" && !flagGiven(fs, "out-dir") && *combined == "":
+		return errors.New("--force only applies to --output, --out-dir or --combined")
+	case *backup && *outFile == "" && !flagGiven(fs, "out-dir") && *combined == "" && !*inPlace:
+		return errors.New("--backup only applies to --output, --out-dir, --combined or --in-place")
+	case (*force || *backup) && (*crawl != "" || *all) && *combined == "":
+		return errors.New("--force and --backup only apply to a single output file, not to --crawl or --all without --combined")
+	case *create && !*writeReg:
+		return errors.New("--create only applies to --write-registry")
+	case *previewSeconds < 0:
+		return errors.New("--preview-seconds can't be negative")
+	case *previewSeconds > 0 && !*apply:
+		return errors.New("--preview-seconds only applies to --apply")
+	case *apply && (*crawl != "" || *all):
+		return errors.New("--apply previews a single theme, it can't be combined with --crawl or --all")
+	case *watch && (*crawl != "" || *all || *writeReg || *diffReg):
+		return errors.New("--watch converts a single theme into a file or stdout, it can't be combined with --crawl, --all, --write-registry or --diff-registry")
+	case *list && (*crawl != "" || *all || *writeReg || *diffReg || *merge != "" || *outFile != "" || *apply || *preview):
+		return errors.New("--list only prints the colors of the input, it can't be combined with --crawl, --all, --write-registry, --diff-registry, --merge, --output, --apply or --preview")
+	case *watch && *previewSeconds > 0:
+		return errors.New("--watch applies the theme again on each change, it can't be combined with --preview-seconds")
+	}
+
+	if *crawl != "" {
+		if len(args) != 0 || len(fnames) != 0 {
+			return errors.New("--crawl doesn't take input files or a session name, the session names come from the theme titles")
+		}
+
+		bt, err := newBatch(*outDir, *from, opts, output)
+		if err != nil {
+			return err
+		}
+
+		bt.combined, bt.force, bt.backup = *combined, *force, *backup
+		return crawlDotshare(*crawl, bt)
+	}
+
+	if *listThemes {
+		return listBuiltinThemes(os.Stdout)
+	}
+
+	if *defaultSettings {
+		if sessionKeys[output.name] == "" && output.name != "kitty-portable" {
+			return errors.New("--default-settings only applies to the PuTTY and KiTTY formats")
+		}
+
+		args = append(args, defaultSession)
+	}
+
+	if *sessionsFile != "" {
+		names, err := readSessionsFile(*sessionsFile)
+		if err != nil {
+			return err
+		}
+
+		sessions = append(sessions, names...)
+	}
+
+	if len(sessions) > 0 {
+		switch {
+		case (len(sessions) > 1 || *sessionsFile != "") && sessionKeys[output.name] == "":
+			return errors.New("several sessions, and --sessions-file, only work with --to kitty or --to putty")
+		case *defaultSettings:
+			return errors.New("--default-settings can't be combined with --session or --sessions-file, add a \"" + defaultSession + "\" session instead")
+		case *writeReg && len(sessions) > 1:
+			return errors.New("--write-registry writes a single session at a time")
+		case *diffReg && len(sessions) > 1:
+			return errors.New("--diff-registry compares a single session at a time")
+		}
+
+		seen := map[string]bool{}
+		for _, name := range sessions {
+			if seen[strings.ToLower(name)] {
+				return fmt.Errorf("session %q is given more than once", name)
+			}
+
+			seen[strings.ToLower(name)] = true
+		}
+
+		args = append(args, sessions[0])
+	}
+
+	fnames, sname, sessionGiven, err := inputArgs(fnames, args, *theme, stdinIsPiped)
+	if err != nil {
+		return err
+	}
+
+	batchInput := len(fnames) == 1 && isBatchInput(fnames[0])
+
+	switch {
+	case *combined != "" && !batchInput && !*all:
+		return errors.New("--combined only applies to a directory or glob input, --all or --crawl")
+	case *strict && !batchInput:
+		return errors.New("--strict only applies to a directory or glob input")
+	case flagGiven(fs, "name-template") && !batchInput:
+		return errors.New("--name-template only applies to a directory or glob input")
+	case batchInput && sessionGiven:
+		return errors.New("a directory or glob input doesn't take a session name, the session names come from the file names")
+	case batchInput && (*member != "" || *all):
+		return errors.New("--all and --member need a single zip or tar archive as input, not a directory or glob")
+	case batchInput && (*writeReg || *diffReg || *merge != "" || *apply || *outFile != "" || *watch || *list):
+		return errors.New("a directory or glob input writes a file for each theme, it can't be combined with --write-registry, --diff-registry, --merge, --apply, --output, --watch or --list")
+	case batchInput:
+		names, err := parseNameTemplate(*nameTmpl)
+		if err != nil {
+			return withCode(exitUsage, err)
+		}
+
+		bt, err := newBatch(*outDir, *from, opts, output)
+		if err != nil {
+			return err
+		}
+
+		bt.combined, bt.force, bt.backup = *combined, *force, *backup
+		return convertFiles(fnames[0], bt, names, *strict)
+	}
+
+	if *all || *member != "" {
+		switch {
+		case *all && *member != "":
+			return errors.New("--all and --member can't be combined")
+		case len(fnames) != 1 || !isArchive(fnames[0]):
+			return errors.New("--all and --member need a single zip or tar archive as input")
+		case *all:
+			bt, err := newBatch(*outDir, *from, opts, output)
+			if err != nil {
+				return err
+			}
+
+			bt.combined, bt.force, bt.backup = *combined, *force, *backup
+
+			return convertArchive(fnames[0], bt)
+		}
+	}
+
+	// without an output to write, --preview only previews the theme
+	previewOnly := *preview && !flagGiven(fs, "to") && *outFile == "" && !flagGiven(fs, "out-dir")
+
+	// convertOnce reads the input and writes the output, once or, with
+	// --watch, each time the input changes.
+	convertOnce := func() error {
+		sname, sessions := sname, sessions
+
+		values, name, err := in.readValues(fnames)
+		if err != nil {
+			return err
+		}
+
+		if *list && len(values) > 0 {
+			return listValues(os.Stdout, values, *asJSON)
+		}
+
+		if !sessionGiven && name != "" {
+			sname = name
+		}
+
+		if previewOnly && sname == "" {
+			sname = sourcesName(fnames)
+		}
+
+		if sname == "" {
+			return errors.New("session name is empty and the input has no theme name to use instead")
+		}
+
+		if len(values) == 0 {
+			return withCode(exitParse, fmt.Errorf("%s format is invalid: no color codes found", sourcesName(fnames)))
+		}
+
+		p, err := convert(values)
+		if err != nil {
+			return err
+		}
+
+		if *preview && (previewOnly || !quiet) {
+			w := os.Stderr
+			if previewOnly {
+				w = os.Stdout
+			}
+
+			writePreview(w, sname, p, isTerminal(w) && os.Getenv("NO_COLOR") == "")
+
+			if previewOnly {
+				return nil
+			}
+		}
+
+		if len(sessions) == 0 {
+			sessions = stringList{sname}
+		}
+
+		if output.isSessionFormat() {
+			for _, name := range sessions {
+				if err := validateSessionName(name); err != nil {
+					return err
+				}
+			}
+		}
+
+		if contains(sessions, defaultSession) && (sessionKeys[output.name] != "" || output.name == "kitty-portable") {
+			warnf("writing the %s session, every new session will start with these colors", defaultSession)
+		}
+
+		output.opts.sessions = sessions
+
+		for _, name := range sessions {
+			switch {
+			case sessionKeys[output.name] != "":
+				debugf("session %q is stored under %s\\%s", name, output.opts.sessionsKey(sessionKeys[output.name]), puttyEscape(name))
+			case output.name == "kitty-portable":
+				debugf("session %q is stored as the file %s", name, portableFileName(name))
+			}
+		}
+
+		if *writeReg {
+			if sname == defaultSession && !*yes && !confirm(fmt.Sprintf("Change the colors of %s for every new session?", defaultSession)) {
+				return fmt.Errorf("not changing %s, pass --yes to skip the confirmation", defaultSession)
+			}
+
+			settings, err := output.opts.sessionSettings(sname)
+			if err != nil {
+				return err
+			}
+
+			warnDropped(p, sessionVendors[output.name])
+			return withCode(exitOutput, writeRegistry(os.Stdout, output.opts.regRoot, output.opts.regPath, sname, p, settings, *create))
+		}
+
+		if *diffReg {
+			warnDropped(p, sessionVendors[output.name])
+
+			differs, err := diffRegistry(os.Stdout, output.opts.regRoot, output.opts.regPath, sname, p)
+			if err != nil {
+				return withCode(exitInput, err)
+			}
+
+			if differs {
+				return errSessionDiffers
+			}
+
+			return nil
+		}
+
+		var b bytes.Buffer
+		if *merge != "" {
+			content, err := os.ReadFile(*merge)
+			if err != nil {
+				return withCode(exitInput, fmt.Errorf("can't read the file to merge into: %s", err.Error()))
+			}
+
+			warnDropped(p, sessionVendors[output.name])
+
+			for _, name := range sessions {
+				settings, err := output.opts.sessionSettings(name)
+				if err != nil {
+					return err
+				}
+
+				content, err = mergeReg(content, output.opts.sessionsKey(sessionKeys[output.name])+`\`+puttyEscape(name), sessionRegLines(p, settings))
+				if err != nil {
+					return withCode(exitParse, fmt.Errorf("can't merge into %q: %s", *merge, err.Error()))
+				}
+			}
+
+			// the merged file keeps its own encoding unless one is asked for
+			if flagGiven(fs, "encoding") {
+				content = reencodeReg(content, output.opts.utf16)
+			}
+
+			if *inPlace {
+				*outFile, *force = *merge, true
+			}
+
+			b.Write(content)
+		} else if err := output.encode(&b, sname, p); err != nil {
+			return withCode(exitOutput, err)
+		}
+
+		if *outFile != "" || flagGiven(fs, "out-dir") {
+			path := *outFile
+			if path == "" {
+				name := output.outputFileName(sname)
+				if err := validateFileName(name); err != nil {
+					return err
+				}
+
+				path = filepath.Join(*outDir, name)
+			}
+
+			if err := writeOutputFile(path, b.Bytes(), *force, *backup); err != nil {
+				return err
+			}
+
+			debugf("wrote the %s output for session %q to %s", output.name, sname, path)
+		} else {
+			debugf("writing the %s output for session %q to stdout", output.name, sname)
+			if _, err := os.Stdout.Write(b.Bytes()); err != nil {
+				return withCode(exitOutput, fmt.Errorf("can't write to stdout: %s", err.Error()))
+			}
+		}
+
+		if *apply {
+			return applyPalette(ctx, p, time.Duration(*previewSeconds)*time.Second)
+		}
+
+		return nil
 	}
 
-	fname, sname := os.Args[1], os.Args[2]
+	if !*watch {
+		return convertOnce()
+	}
+
+	toStdout := previewOnly || *outFile == "" && !flagGiven(fs, "out-dir") && !*inPlace
 
-	if sname == "" {
-		return errors.New("session name is empty")
+	return watchInput(ctx, pollWatcher{interval: watchInterval, settle: watchSettle}, fnames, opts.includes, func(round int) error {
+		if round > 0 && toStdout {
+			fmt.Fprintf(os.Stdout, "\n----- converted again at %s -----\n\n", time.Now().Format("15:04:05"))
+		}
+
+		if err := convertOnce(); err != nil {
+			return err
+		}
+
+		// the output file is this run's own now, later runs replace it
+		// without keeping a backup of each
+		*force, *backup = true, false
+		return nil
+	})
+}
+
+// decodeInput reads and decodes a single input file in the given format,
+// or the detected one when empty, returning its resources along with the
+// name of the theme when the format stores one.
+func decodeInput(fname, from string, opts decodeOptions) (map[string]resource, string, error) {
+	lines, err := readInput(fname)
+	if err != nil {
+		return nil, "", withCode(exitInput, err)
 	}
 
-	f, err := os.Open(fname)
+	return decodeLines(fname, lines, from, opts)
+}
+
+// decodeLines decodes lines already read from the input file.
+func decodeLines(fname string, lines []sourceLine, from string, opts decodeOptions) (map[string]resource, string, error) {
+	format, err := findInputFormat(from, lines)
 	if err != nil {
-		return fmt.Errorf("can't open file %q: %s", fname, err.Error())
+		return nil, "", withCode(exitParse, fmt.Errorf("can't read %s: %s", sourceName(fname), err.Error()))
+	}
+
+	if from == "" {
+		debugf("reading %s as %s, detected automatically", sourceName(fname), format.name)
+	} else {
+		debugf("reading %s as %s", sourceName(fname), format.name)
 	}
 
-	defer f.Close()
+	values, err := format.decode(lines, opts)
+	if err != nil {
+		return nil, "", withCode(exitParse, fmt.Errorf("can't parse %s: %s", sourceName(fname), err.Error()))
+	}
 
-	var b bytes.Buffer
-	if _, err := io.Copy(&b, f); err != nil {
-		return fmt.Errorf("can't read file %q: %s", fname, err.Error())
+	for key, res := range values {
+		res.format = format.name
+		values[key] = res
 	}
 
-	objects := reParseItems.FindAllStringSubmatch(b.String(), -1)
-	if len(objects) == 0 {
-		return fmt.Errorf("file %q format is invalid: no color codes found", fname)
+	var name string
+	if format.themeName != nil {
+		name = format.themeName(lines, opts)
 	}
 
-	values := map[string]string{}
-	for idx, v := range objects {
-		if len(v) != 3 {
-			return fmt.Errorf("no color code format found in mapping submatch at position %d: mappings: %#v", idx, v)
+	return values, name, nil
+}
+
+// convert maps the resources to the KiTTY session colors, in the numeric
+// order of their index, so Colour2 comes before Colour10. All the keys in
+// nameReplacements must be present, unless the input format is partial,
+// and the ones in optionalReplacements override the slots they share with
+// them.
+func convert(values map[string]resource) (palette, error) {
+	if verbose > 0 {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
 		}
 
-		values[v[1]] = v[2]
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			debugf("final value for %s: %s (from %s)", key, values[key].value, values[key].source)
+		}
 	}
 
 	notFoundKeys := make([]string, 0, len(values))
 	kvals := make([]colormatch, 0, len(nameReplacements)+3)
 
 	for keyName, keyItems := range nameReplacements {
-		hexColor, found := values[keyName]
+		res, found := values[keyName]
 
 		if !found {
 			notFoundKeys = append(notFoundKeys, keyName)
 			continue
 		}
 
-		converted, err := hexToRGB(hexColor)
+		converted, err := parseColor(res.value)
 		if err != nil {
-			return fmt.Errorf("unable to parse hex color %q: %s", hexColor, err.Error())
+			return palette{}, res.invalid(keyName, err)
 		}
 
 		for _, m := range keyItems {
 			kvals = append(kvals, colormatch{
 				name:  fmt.Sprintf("%s%d", colorPrefix, m),
+				index: m,
 				color: converted,
 			})
 		}
 	}
 
-	if len(notFoundKeys) != 0 {
-		return fmt.Errorf("the following keys weren't found in the config file: %s", strings.Join(notFoundKeys, ", "))
+	// the map is iterated in random order, so the keys are listed by the
+	// slot they fill
+	sort.Slice(notFoundKeys, func(i, j int) bool {
+		return nameReplacements[notFoundKeys[i]][0] < nameReplacements[notFoundKeys[j]][0]
+	})
+
+	switch {
+	case len(notFoundKeys) != 0 && isPartial(values):
+		debugf("leaving out %s, the input only has some of the colors", strings.Join(notFoundKeys, ", "))
+	case len(notFoundKeys) != 0:
+		return palette{}, withCode(exitMissingKeys, fmt.Errorf("the following keys weren't found in the config file: %s", strings.Join(notFoundKeys, ", ")))
+	}
+
+	for keyName, keyItems := range optionalReplacements {
+		res, found := values[keyName]
+		if !found {
+			continue
+		}
+
+		converted, err := parseColor(res.value)
+		if err != nil {
+			return palette{}, res.invalid(keyName, err)
+		}
+
+		debugf("%s: using %s for %s", res.source, keyName, slotNames(keyItems))
+
+		for _, m := range keyItems {
+			for i := range kvals {
+				if kvals[i].index == m {
+					kvals[i].color = converted
+				}
+			}
+		}
+	}
+
+	for _, keyName := range ignoredKeys {
+		if res, found := values[keyName]; found {
+			debugf("%s: ignoring %s, KiTTY has no equivalent setting", res.source, keyName)
+		}
 	}
 
-	sort.Slice(kvals, func(i, j int) bool {
-		return kvals[i].name < kvals[j].name
+	sort.SliceStable(kvals, func(i, j int) bool {
+		return kvals[i].index < kvals[j].index
 	})
 
-	b.Reset()
+	keys := make(map[string]color.RGBA, len(values))
+	seen := map[paletteSource]bool{}
+	var sources []paletteSource
 
-	fmt.Fprintln(&b, "Windows Registry Editor Version 5.00")
-	fmt.Fprintln(&b, "")
-	fmt.Fprintf(&b, "[HKEY_CURRENT_USER\\Software\\9bis.com\\KiTTY\\Sessions\\%s]\n", url.PathEscape(sname))
+	for keyName, res := range values {
+		if !isKnownKey(keyName) {
+			continue
+		}
+
+		converted, err := parseColor(res.value)
+		if err != nil {
+			return palette{}, res.invalid(keyName, err)
+		}
+
+		keys[keyName] = converted
 
-	for _, color := range kvals {
-		fmt.Fprintf(&b, "%q=%q\n", color.name, color.getRGB())
+		src := paletteSource{File: res.source.file, Format: res.format}
+		if src.File == "-" {
+			src.File = "stdin"
+		}
+
+		if !seen[src] {
+			seen[src] = true
+			sources = append(sources, src)
+		}
 	}
 
-	fmt.Fprintln(os.Stdout, b.String())
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].File < sources[j].File
+	})
 
-	return nil
+	return palette{slots: kvals, keys: keys, sources: sources}, nil
 }
 
-func hexToRGB(s string) (c color.RGBA, err error) {
-	c.A = 0xff
+// isPartial reports whether the resources were read from a format whose
+// files can hold only some of the colors.
+func isPartial(values map[string]resource) bool {
+	for _, res := range values {
+		if res.format == "" {
+			continue
+		}
 
-	if s[0] != '#' {
-		return c, errors.New("invalid format")
+		if f, err := findInputFormat(res.format, nil); err == nil && f.partial {
+			return true
+		}
 	}
 
-	hexToByte := func(b byte) byte {
-		switch {
-		case b >= '0' && b <= '9':
-			return b - '0'
-		case b >= 'a' && b <= 'f':
-			return b - 'a' + 10
-		case b >= 'A' && b <= 'F':
-			return b - 'A' + 10
-		}
-		err = errors.New("invalid format")
-		return 0
-	}
-
-	switch len(s) {
-	case 7:
-		c.R = hexToByte(s[1])<<4 + hexToByte(s[2])
-		c.G = hexToByte(s[3])<<4 + hexToByte(s[4])
-		c.B = hexToByte(s[5])<<4 + hexToByte(s[6])
-	case 4:
-		c.R = hexToByte(s[1]) * 17
-		c.G = hexToByte(s[2]) * 17
-		c.B = hexToByte(s[3]) * 17
+	return false
+}
+
+// parseInterspersed parses the flags wherever they appear among the
+// arguments, so "urxvt-kitty themes.zip --all" works the same as with the
+// flag first, and returns the remaining arguments. Arguments after "--"
+// are never treated as flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		positional, args = append(positional, rest[0]), rest[1:]
+	}
+}
+
+// flagGiven reports whether the flag was set on the command line, rather
+// than left to its default.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			given = true
+		}
+	})
+
+	return given
+}
+
+// stdinIsPiped reports whether stdin is a pipe or a file rather than an
+// interactive terminal.
+func stdinIsPiped() bool {
+	return !isTerminal(os.Stdin)
+}
+
+// inputArgs works out the input files and the session name from the files
+// given with --input, the positional arguments and --theme. A single
+// positional argument is an input file, unless stdin is piped and the
+// argument can't name an input, in which case it's the session name for
+// the theme read from stdin. sessionGiven is false when the session name
+// is left to be picked from the input.
+func inputArgs(fnames stringList, args []string, theme string, piped func() bool) (inputs stringList, sname string, sessionGiven bool, err error) {
+	sessionGiven = true
+
+	switch {
+	case theme != "" && (len(fnames) > 0 || len(args) > 1):
+		return nil, "", false, errors.New("--theme can't be combined with input files")
+	case theme != "":
+		fnames, sname = stringList{builtinPrefix + theme}, theme
+		if len(args) == 1 {
+			sname = args[0]
+		}
+	case len(fnames) > 0 && len(args) == 1:
+		sname = args[0]
+	case len(fnames) > 0 && len(args) == 0:
+		sessionGiven = false
+	case len(fnames) == 0 && len(args) >= 2:
+		fnames, sname = args[:len(args)-1], args[len(args)-1]
+	case len(fnames) == 0 && len(args) == 1 && (isInputName(args[0]) || !piped()):
+		fnames, sessionGiven = stringList{args[0]}, false
+	case len(fnames) == 0 && len(args) == 1:
+		fnames, sname = stringList{"-"}, args[0]
 	default:
-		err = errors.New("invalid format")
+		return nil, "", false, errUsage
+	}
+
+	if sessionGiven && sname == "" {
+		return nil, "", false, errors.New("session name is empty")
+	}
+
+	return fnames, sname, sessionGiven, nil
+}
+
+// isInputName reports whether the argument names an input rather than a
+// session: stdin, an existing file or directory, a glob pattern, a URL, a
+// "github:" shorthand or a built-in theme.
+func isInputName(arg string) bool {
+	if arg == "-" || isURL(arg) || strings.HasPrefix(arg, builtinPrefix) || isBatchInput(arg) {
+		return true
+	}
+
+	if _, ok := expandGitHub(arg); ok {
+		return true
+	}
+
+	_, err := os.Stat(arg)
+	return err == nil
+}
+
+// readSessionsFile reads the session names listed in the file, one per
+// line, skipping blank lines and "#" comments.
+func readSessionsFile(fname string) ([]string, error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the sessions file: %s", err.Error())
+	}
+
+	var names []string
+	for _, line := range strings.Split(string(normalizeText(content)), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("the sessions file %q has no session names", fname)
 	}
-	return
+
+	return names, nil
+}
+
+// confirm asks the question on the terminal, returning whether the answer
+// was yes. It returns false right away when stdin isn't a terminal, or
+// with --quiet, which leaves nowhere to ask it.
+func confirm(question string) bool {
+	if !isTerminal(os.Stdin) || quiet {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// isTerminal reports whether the file is a terminal rather than a pipe or
+// a regular file.
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return st.Mode()&os.ModeCharDevice != 0
+}
+
+// stringList is a flag that can be repeated, collecting all its values.
+type stringList []string
+
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ", ")
+}
+
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
+func slotNames(slots []int) string {
+	names := make([]string, 0, len(slots))
+	for _, m := range slots {
+		names = append(names, fmt.Sprintf("%s%d", colorPrefix, m))
+	}
+
+	return strings.Join(names, ", ")
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the current output")
+
+// readTestValues reads a file from testdata the way the convert command
+// does, detecting its format.
+func readTestValues(t *testing.T, name string) map[string]resource {
+	t.Helper()
+
+	fname := filepath.Join("testdata", name)
+	lines, err := readLines(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, _, err := decodeLines(fname, lines, "", decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
+
+// readTestPalette reads and converts a file from testdata.
+func readTestPalette(t *testing.T, name string) palette {
+	t.Helper()
+
+	p, err := convert(readTestValues(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// checkGolden compares the output with the golden file in testdata, byte
+// for byte, or rewrites the golden file when running with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run the tests with -update if the change is intended\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// builtinTestPalette reads and converts one of the embedded themes.
+func builtinTestPalette(t *testing.T, name string) palette {
+	t.Helper()
+
+	lines, err := readBuiltinTheme(builtinPrefix + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := parseXresources(lines, decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestInputArgs(t *testing.T) {
+	theme := filepath.Join("testdata", "mixed-case.Xresources")
+	missing := filepath.Join("testdata", "missing.Xresources")
+
+	tests := []struct {
+		name         string
+		fnames       stringList
+		args         []string
+		theme        string
+		piped        bool
+		want         stringList
+		sname        string
+		sessionGiven bool
+		wantErr      bool
+	}{
+		{name: "existing file with piped stdin", args: []string{theme}, piped: true, want: stringList{theme}},
+		{name: "existing file", args: []string{theme}, want: stringList{theme}},
+		{name: "missing file", args: []string{missing}, want: stringList{missing}},
+		{name: "session for piped stdin", args: []string{"Work"}, piped: true, want: stringList{"-"}, sname: "Work", sessionGiven: true},
+		{name: "stdin with piped stdin", args: []string{"-"}, piped: true, want: stringList{"-"}},
+		{name: "builtin with piped stdin", args: []string{"builtin:nord"}, piped: true, want: stringList{"builtin:nord"}},
+		{name: "url with piped stdin", args: []string{"https://example.com/nord.Xresources"}, piped: true, want: stringList{"https://example.com/nord.Xresources"}},
+		{name: "github with piped stdin", args: []string{"github:arcticicestudio/nord-xresources/src/nord"}, piped: true, want: stringList{"github:arcticicestudio/nord-xresources/src/nord"}},
+		{name: "file and session", args: []string{theme, "Work"}, piped: true, want: stringList{theme}, sname: "Work", sessionGiven: true},
+		{name: "files and session", args: []string{theme, missing, "Work"}, want: stringList{theme, missing}, sname: "Work", sessionGiven: true},
+		{name: "input flag and session", fnames: stringList{theme}, args: []string{"Work"}, want: stringList{theme}, sname: "Work", sessionGiven: true},
+		{name: "input flag", fnames: stringList{theme}, want: stringList{theme}},
+		{name: "theme", theme: "nord", want: stringList{"builtin:nord"}, sname: "nord", sessionGiven: true},
+		{name: "theme and session", theme: "nord", args: []string{"Work"}, want: stringList{"builtin:nord"}, sname: "Work", sessionGiven: true},
+		{name: "theme and file", theme: "nord", args: []string{theme, "Work"}, wantErr: true},
+		{name: "theme and input flag", theme: "nord", fnames: stringList{theme}, wantErr: true},
+		{name: "empty session", args: []string{theme, ""}, wantErr: true},
+		{name: "nothing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, sname, sessionGiven, err := inputArgs(tt.fnames, tt.args, tt.theme, func() bool { return tt.piped })
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: inputArgs() succeeded, want an error", tt.name)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, tt.want) || sname != tt.sname || sessionGiven != tt.sessionGiven {
+			t.Errorf("%s: inputArgs() = %q, %q, %t, %v, want %q, %q, %t", tt.name, got, sname, sessionGiven, err, tt.want, tt.sname, tt.sessionGiven)
+		}
+	}
+}
+
+// captureStderr returns what f prints to stderr.
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = prev }()
+
+	done := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- b
+	}()
+
+	f()
+	w.Close()
+
+	return string(<-done)
+}
+
+func TestConvertMissingKeys(t *testing.T) {
+	_, err := convert(readTestValues(t, "missing-keys.Xresources"))
+
+	want := "the following keys weren't found in the config file: color8, color9, color10, color11, color12, color13, color14, color15"
+	if exitStatus(err) != exitMissingKeys || err.Error() != want {
+		t.Errorf("convert() = %v with exit status %d, want %q with exit status %d", err, exitStatus(err), want, exitMissingKeys)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// regLine is a value to write into a .reg file, with its data already in
+// the .reg notation.
+type regLine struct {
+	name string
+	data string
+}
+
+func (l regLine) String() string {
+	return fmt.Sprintf("%q=%s", l.name, l.data)
+}
+
+// sessionRegLines returns the values the registry formats write for the
+// session: the colors followed by the extra settings.
+func sessionRegLines(p palette, settings []sessionSetting) []regLine {
+	lines := make([]regLine, 0, len(p.slots)+len(settings))
+	for _, color := range p.slots {
+		lines = append(lines, regLine{name: color.name, data: fmt.Sprintf("%q", color.getRGB())})
+	}
+
+	for _, setting := range settings {
+		if setting.isText {
+			lines = append(lines, regLine{name: setting.name, data: fmt.Sprintf("%q", setting.text)})
+		} else {
+			lines = append(lines, regLine{name: setting.name, data: fmt.Sprintf("dword:%08x", setting.value)})
+		}
+	}
+
+	return lines
+}
+
+// reencodeReg converts a .reg file to UTF-16 with CRLF line endings, as
+// the other .reg outputs are written with --encoding utf16, or to UTF-8.
+func reencodeReg(content []byte, utf16 bool) []byte {
+	text := decodeUTF16(content)
+	if utf16 {
+		return encodeUTF16(string(text))
+	}
+
+	return text
+}
+
+// mergeReg sets the values in the section for key, which starts with its
+// root key, of an existing .reg file, keeping everything else as it was:
+// values the section already has are replaced where they are, the others
+// are added at the end of it, and when the file has no such section, it's
+// added at the end of the file. The file keeps its line endings, and its
+// encoding when it's UTF-16 like the ones exported by regedit.
+func mergeReg(content []byte, key string, values []regLine) ([]byte, error) {
+	isUTF16 := bytes.HasPrefix(content, []byte{0xff, 0xfe})
+	if !isUTF16 && bytes.HasPrefix(content, []byte{0xfe, 0xff}) {
+		return nil, fmt.Errorf("big-endian UTF-16 .reg files aren't supported")
+	}
+
+	text := string(decodeUTF16(content))
+
+	eol := "\n"
+	if strings.Contains(text, "\r\n") {
+		eol = "\r\n"
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	header := "[" + key + "]"
+
+	start := -1
+	for i, line := range lines {
+		if strings.EqualFold(strings.TrimRight(line, "\r\n"), header) {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		debugf("%s isn't in the file yet, adding it", header)
+
+		if len(text) != 0 && !strings.HasSuffix(text, "\n") {
+			text += eol
+		}
+
+		if !strings.HasSuffix(text, eol+eol) {
+			text += eol
+		}
+
+		text += header + eol
+		for _, value := range values {
+			text += value.String() + eol
+		}
+
+		return encodeMerged(text+eol, isUTF16), nil
+	}
+
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "[") {
+			end = i
+			break
+		}
+	}
+
+	pending := make([]regLine, 0, len(values))
+	for _, value := range values {
+		replaced := false
+		for i := start + 1; i < end; i++ {
+			m := reRegValue.FindStringSubmatch(strings.TrimRight(lines[i], "\r\n"))
+			if m == nil || !strings.EqualFold(m[1], value.name) {
+				continue
+			}
+
+			ending := lines[i][len(strings.TrimRight(lines[i], "\r\n")):]
+			lines[i] = value.String() + ending
+			replaced = true
+		}
+
+		if !replaced {
+			pending = append(pending, value)
+		}
+	}
+
+	// the new values go after the last value of the section, before the
+	// blank lines separating it from the next one
+	last := end - 1
+	for last > start && strings.TrimSpace(lines[last]) == "" {
+		last--
+	}
+
+	added := make([]string, 0, len(pending))
+	for _, value := range pending {
+		added = append(added, value.String()+eol)
+	}
+
+	if len(added) != 0 && !strings.HasSuffix(lines[last], "\n") {
+		lines[last] += eol
+	}
+
+	merged := append(append(append([]string{}, lines[:last+1]...), added...), lines[last+1:]...)
+	return encodeMerged(strings.Join(merged, ""), isUTF16), nil
+}
+
+func encodeMerged(text string, isUTF16 bool) []byte {
+	if isUTF16 {
+		return encodeUTF16(text)
+	}
+
+	return []byte(text)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// minttySettings maps the mintty theme settings to the Xresources keys
+// they set. The color names are added in init.
+var minttySettings = map[string]string{
+	"ForegroundColour": "foreground",
+	"BackgroundColour": "background",
+	"CursorColour":     "cursorColor",
+	"BoldColour":       "colorBD",
+}
+
+func init() {
+	for i, name := range ansiColorNames {
+		name = strings.ToUpper(name[:1]) + name[1:]
+		minttySettings[name] = fmt.Sprintf("color%d", i)
+		minttySettings["Bold"+name] = fmt.Sprintf("color%d", i+8)
+	}
+}
+
+func detectMintty(content string) bool {
+	return strings.Contains(content, "ForegroundColour=") || strings.Contains(content, "BoldBlack=")
+}
+
+// parseMintty reads a mintty theme or .minttyrc file, where colors are
+// mostly written as decimal "r,g,b" triplets.
+func parseMintty(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+
+	for _, e := range parseINI(lines) {
+		key, found := minttySettings[e.key]
+		if !found {
+			continue
+		}
+
+		c, err := parseColor(e.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid color for mintty setting %s: %s", e.source, e.key, err.Error())
+		}
+
+		debugf("%s: using %s: %s", e.source, e.key, e.value)
+		values[key] = resource{value: hexColor(c), source: e.source}
+	}
+
+	return values, nil
+}
+
+// writeMintty writes the colors as a mintty theme, which can be placed in
+// mintty's themes directory or pasted into .minttyrc.
+func writeMintty(w io.Writer, _ string, p palette, _ encodeOptions) error {
+	settings := []string{"ForegroundColour", "BackgroundColour", "CursorColour"}
+	for _, prefix := range []string{"", "Bold"} {
+		for _, name := range ansiColorNames {
+			settings = append(settings, prefix+strings.ToUpper(name[:1])+name[1:])
+		}
+	}
+
+	for _, name := range settings {
+		c := p.keys[minttySettings[name]]
+		fmt.Fprintf(w, "%s=%d,%d,%d\n", name, c.R, c.G, c.B)
+	}
+
+	return nil
+}
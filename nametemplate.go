@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultNameTemplate names each session of a directory or glob batch
+// after its file.
+const defaultNameTemplate = "{basename}"
+
+// nameFields are the placeholders of a --name-template, given the path of
+// the file and its position in the batch, starting from 1.
+var nameFields = map[string]func(fname string, index int) string{
+	"basename": func(fname string, _ int) string {
+		base := filepath.Base(fname)
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	},
+	"dir":   func(fname string, _ int) string { return filepath.Base(filepath.Dir(fname)) },
+	"ext":   func(fname string, _ int) string { return strings.TrimPrefix(filepath.Ext(fname), ".") },
+	"index": func(_ string, index int) string { return strconv.Itoa(index) },
+}
+
+// nameModifiers are the modifiers that can follow a placeholder, as in
+// "{basename|lower|dash}", applied from left to right.
+var nameModifiers = map[string]func(string) string{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"dash":  func(s string) string { return strings.Join(strings.Fields(s), "-") },
+	"trim":  strings.TrimSpace,
+}
+
+// nameTemplate shapes the session names of a directory or glob batch. It
+// yields the name before it's sanitized and escaped for the registry.
+type nameTemplate struct {
+	parts []namePart
+}
+
+// namePart is either literal text, or a placeholder with its modifiers.
+type namePart struct {
+	text      string
+	field     string
+	modifiers []string
+}
+
+// parseNameTemplate parses a --name-template, rejecting unknown
+// placeholders and modifiers up front. "{{" and "}}" stand for literal
+// braces.
+func parseNameTemplate(s string) (*nameTemplate, error) {
+	t := &nameTemplate{}
+	var text strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			text.WriteByte(s[i])
+			i++
+		case s[i] == '}':
+			return nil, fmt.Errorf("invalid name template %q: unexpected \"}\", write \"}}\" for a literal one", s)
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid name template %q: unclosed \"{\"", s)
+			}
+
+			if text.Len() > 0 {
+				t.parts = append(t.parts, namePart{text: text.String()})
+				text.Reset()
+			}
+
+			part, err := parseNamePlaceholder(s[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid name template %q: %s", s, err.Error())
+			}
+
+			t.parts = append(t.parts, part)
+			i += end
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+
+	if text.Len() > 0 {
+		t.parts = append(t.parts, namePart{text: text.String()})
+	}
+
+	return t, nil
+}
+
+func parseNamePlaceholder(s string) (namePart, error) {
+	fields := strings.Split(s, "|")
+	part := namePart{field: strings.TrimSpace(fields[0])}
+
+	if _, found := nameFields[part.field]; !found {
+		return namePart{}, fmt.Errorf("unknown placeholder {%s}, use one of: %s", part.field, strings.Join(sortedKeys(nameFields), ", "))
+	}
+
+	for _, m := range fields[1:] {
+		m = strings.TrimSpace(m)
+		if _, found := nameModifiers[m]; !found {
+			return namePart{}, fmt.Errorf("unknown modifier %q in {%s}, use one of: %s", m, s, strings.Join(sortedKeys(nameModifiers), ", "))
+		}
+
+		part.modifiers = append(part.modifiers, m)
+	}
+
+	return part, nil
+}
+
+// name returns the session name for the file at the given position of the
+// batch, starting from 1.
+func (t *nameTemplate) name(fname string, index int) string {
+	var b strings.Builder
+
+	for _, part := range t.parts {
+		if part.field == "" {
+			b.WriteString(part.text)
+			continue
+		}
+
+		value := nameFields[part.field](fname, index)
+		for _, m := range part.modifiers {
+			value = nameModifiers[m](value)
+		}
+
+		b.WriteString(value)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// oscSequence is an OSC escape sequence setting one of the terminal
+// colors: OSC 4 sets a palette entry and the others the dynamic colors.
+type oscSequence struct {
+	code  string
+	color string
+}
+
+// oscDynamicColors maps the OSC codes of the dynamic colors to the keys
+// they're set from.
+var oscDynamicColors = [][2]string{
+	{"10", "foreground"},
+	{"11", "background"},
+	{"12", "cursorColor"},
+	{"17", "highlightColor"},
+	{"19", "highlightTextColor"},
+}
+
+// oscSequences returns the sequences setting all the colors in the
+// palette, starting with the 16 palette entries.
+func oscSequences(p palette) []oscSequence {
+	var seqs []oscSequence
+	for i, key := range paletteKeys() {
+		seqs = append(seqs, oscSequence{code: fmt.Sprintf("4;%d", i), color: hexColor(p.keys[key])})
+	}
+
+	for _, d := range oscDynamicColors {
+		if c, found := p.keys[d[1]]; found {
+			seqs = append(seqs, oscSequence{code: d[0], color: hexColor(c)})
+		}
+	}
+
+	return seqs
+}
+
+// writeOSC writes the escape sequences that recolor a running terminal,
+// so the theme can be previewed with cat. With the shell option, they're
+// written as printf commands instead, safe to store in a script.
+func writeOSC(w io.Writer, sname string, p palette, opts encodeOptions) error {
+	seqs := oscSequences(p)
+
+	if !opts.shell {
+		var sb strings.Builder
+		for _, seq := range seqs {
+			fmt.Fprintf(&sb, "\033]%s;%s\007", seq.code, seq.color)
+		}
+
+		fmt.Fprintln(w, sb.String())
+		return nil
+	}
+
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintf(w, "# %s\n", strings.ReplaceAll(sname, "\n", " "))
+
+	for _, seq := range seqs {
+		fmt.Fprintf(w, "printf '\\033]%s;%s\\007'\n", seq.code, seq.color)
+	}
+
+	return nil
+}
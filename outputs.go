@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// sessionKeys are the registry keys, under HKEY_CURRENT_USER, where PuTTY
+// and its forks keep their saved sessions, used unless --registry-path
+// picks another one. They all store the colors as the same Colour0
+// through Colour21 values.
+var sessionKeys = map[string]string{
+	"kitty": `Software\9bis.com\KiTTY\Sessions`,
+	"putty": `Software\SimonTatham\PuTTY\Sessions`,
+}
+
+// registryRoots are the root keys the sessions can be written under, by
+// the names --registry-root takes.
+var registryRoots = map[string]string{
+	"hkcu": "HKEY_CURRENT_USER",
+	"hklm": "HKEY_LOCAL_MACHINE",
+}
+
+// defaultSession is the session PuTTY and its forks copy the settings of
+// new sessions from.
+const defaultSession = "Default Settings"
+
+// sessionVendors are the names of the programs the sessionKeys belong to.
+var sessionVendors = map[string]string{
+	"kitty": "KiTTY",
+	"putty": "PuTTY",
+}
+
+// encodeOptions are the settings shared by all the output formats.
+type encodeOptions struct {
+	// settings are extra numeric session settings written along with the
+	// colors by the PuTTY and KiTTY formats.
+	settings []sessionSetting
+
+	// template, when set, has more settings written after those.
+	template *sessionTemplate
+
+	// regRoot and regPath are the root key and the key under it where the
+	// registry formats store the sessions, HKEY_CURRENT_USER and the key
+	// of the format when empty.
+	regRoot string
+	regPath string
+
+	// clean makes the registry formats delete each session before writing
+	// it, dropping any value left over from earlier.
+	clean bool
+
+	// sessions are the sessions the registry formats write, each in its own
+	// section of the same file.
+	sessions []string
+
+	utf16     bool
+	author    string
+	shell     bool
+	cssPrefix string
+	cssClass  string
+	pngScale  int
+}
+
+// sessionSetting is a PuTTY session setting, stored as a dword, or as a
+// string when isText is set.
+type sessionSetting struct {
+	name   string
+	value  uint32
+	text   string
+	isText bool
+}
+
+// sessionsKey returns the full registry key the sessions are stored
+// under, using key when no other one was picked.
+func (opts encodeOptions) sessionsKey(key string) string {
+	root := opts.regRoot
+	if root == "" {
+		root = registryRoots["hkcu"]
+	}
+
+	if opts.regPath != "" {
+		key = opts.regPath
+	}
+
+	return root + `\` + key
+}
+
+// sessionSettings returns the settings to write along with the colors of
+// the named session.
+func (opts encodeOptions) sessionSettings(sname string) ([]sessionSetting, error) {
+	if opts.template == nil {
+		return opts.settings, nil
+	}
+
+	extra, err := opts.template.settings(sname)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]sessionSetting{}, opts.settings...), extra...), nil
+}
+
+// paletteSettings returns the session settings that make PuTTY and KiTTY
+// use the session palette: without them, a session or default settings
+// using the system colors or limiting the palette would ignore it. Bold
+// text is shown in the bright colors when boldAsColour is set, and in a
+// bold font otherwise. Only the bold setting is returned unless force is
+// set.
+func paletteSettings(force, boldAsColour bool) []sessionSetting {
+	var settings []sessionSetting
+	if force {
+		settings = append(settings,
+			sessionSetting{name: "UseSystemColours", value: 0},
+			sessionSetting{name: "TryPalette", value: 0},
+			sessionSetting{name: "ANSIColour", value: 1},
+			sessionSetting{name: "Xterm256Colour", value: 1},
+		)
+	}
+
+	bold := sessionSetting{name: "BoldAsColour", value: 0}
+	if boldAsColour {
+		bold.value = 1
+	}
+
+	return append(settings, bold)
+}
+
+// outputFormat is a file format the converted colors can be written as.
+type outputFormat struct {
+	name  string
+	ext   string
+	write func(w io.Writer, sname string, p palette, opts encodeOptions) error
+
+	// fileName, when set, returns the name of the file to write the session
+	// to, instead of the session name followed by ext.
+	fileName func(sname string) string
+
+	// page, when set, writes all the themes converted in batch mode into a
+	// single file named after pageName, instead of a file for each.
+	page     func(w io.Writer, title string, themes []namedPalette, opts encodeOptions) error
+	pageName string
+
+	// combine, when set, writes all the themes converted in batch mode
+	// into the single file given with --combined, as formats with a page
+	// also can.
+	combine func(w io.Writer, themes []namedPalette, opts encodeOptions) error
+
+	// opts are passed on to write, set from the command line flags.
+	opts encodeOptions
+}
+
+// encode writes the palette in this format, transcoded to UTF-16 when
+// asked to.
+func (f outputFormat) encode(w io.Writer, sname string, p palette) error {
+	if !f.opts.utf16 {
+		return f.write(w, sname, p, f.opts)
+	}
+
+	var b bytes.Buffer
+	if err := f.write(&b, sname, p, f.opts); err != nil {
+		return err
+	}
+
+	_, err := w.Write(encodeUTF16(b.String()))
+	return err
+}
+
+// encodeUTF16 converts the text to UTF-16LE with a BOM and CRLF line
+// endings, the way regedit exports .reg files.
+func encodeUTF16(s string) []byte {
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+
+	out := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+
+	return out
+}
+
+// outputFileName returns the name of the file to write the session to.
+func (f outputFormat) outputFileName(sname string) string {
+	if f.fileName != nil {
+		return f.fileName(sname)
+	}
+
+	return sname + f.ext
+}
+
+// outputFormats lists the supported output formats, sorted by name. The
+// default is kitty, set by the --to flag.
+var outputFormats = []outputFormat{
+	{name: "alacritty", ext: ".toml", write: writeAlacritty},
+	{name: "alacritty-yaml", ext: ".yml", write: writeAlacrittyYAML},
+	{name: "base16", ext: ".yaml", write: writeBase16},
+	{name: "conemu", ext: ".xml", write: writeConEmu},
+	{name: "console-reg", ext: ".reg", write: writeConsoleReg},
+	{name: "css", ext: ".css", write: writeCSS},
+	{name: "gpl", ext: ".gpl", write: writeGPL},
+	{name: "html", ext: ".html", write: writeHTML, page: writeHTMLPage, pageName: "themes"},
+	{name: "itermcolors", ext: ".itermcolors", write: writeIterm},
+	{name: "json", ext: ".json", write: writeJSON},
+	{name: "kitty", ext: ".reg", write: registryWriter(sessionVendors["kitty"], sessionKeys["kitty"]), combine: registryCombiner(sessionVendors["kitty"], sessionKeys["kitty"])},
+	{name: "kitty-conf", ext: ".conf", write: writeKittyConf},
+	{name: "kitty-portable", write: writePortableSession, fileName: portableFileName},
+	{name: "mintty", write: writeMintty},
+	{name: "nvim-lua", ext: ".lua", write: vimWriter("--", "vim.g.terminal_color_%d = '%s'")},
+	{name: "osc", write: writeOSC},
+	{name: "png", ext: ".png", write: writePNG},
+	{name: "putty", ext: ".reg", write: registryWriter(sessionVendors["putty"], sessionKeys["putty"]), combine: registryCombiner(sessionVendors["putty"], sessionKeys["putty"])},
+	{name: "securecrt", ext: ".ini", write: writeSecureCRT},
+	{name: "terminalsexy", ext: ".json", write: writeTerminalSexy},
+	{name: "termux", ext: ".properties", write: writeTermux},
+	{name: "vim", ext: ".vim", write: vimWriter(`"`, "let g:terminal_color_%d = '%s'")},
+	{name: "wezterm", ext: ".toml", write: writeWezterm},
+	{name: "windows-terminal", ext: ".json", write: writeWindowsTerminal},
+	{name: "xresources", ext: ".Xresources", write: writeXresources},
+	{name: "xshell", ext: ".xcs", write: writeXshell},
+}
+
+func outputFormatNames() []string {
+	names := make([]string, 0, len(outputFormats))
+	for _, f := range outputFormats {
+		names = append(names, f.name)
+	}
+
+	return names
+}
+
+// findOutputFormat returns the output format with the given name.
+func findOutputFormat(name string) (outputFormat, error) {
+	for _, f := range outputFormats {
+		if f.name == name {
+			return f, nil
+		}
+	}
+
+	return outputFormat{}, fmt.Errorf("unknown output format %q, supported formats are: %s", name, strings.Join(outputFormatNames(), ", "))
+}
+
+// registryWriter returns a writer for .reg files that can be imported with
+// regedit, storing the session under the given key unless the options
+// pick another one.
+func registryWriter(vendor, key string) func(io.Writer, string, palette, encodeOptions) error {
+	return func(w io.Writer, sname string, p palette, opts encodeOptions) error {
+		warnDropped(p, vendor)
+
+		names := opts.sessions
+		if len(names) == 0 {
+			names = []string{sname}
+		}
+
+		sessions := make([]namedPalette, 0, len(names))
+		for _, name := range names {
+			sessions = append(sessions, namedPalette{name: name, palette: p})
+		}
+
+		return writeRegSessions(w, key, sessions, opts)
+	}
+}
+
+// registryCombiner returns a writer for a single .reg file holding a
+// session for each of the themes.
+func registryCombiner(vendor, key string) func(io.Writer, []namedPalette, encodeOptions) error {
+	return func(w io.Writer, themes []namedPalette, opts encodeOptions) error {
+		for _, theme := range themes {
+			warnDropped(theme.palette, vendor)
+		}
+
+		return writeRegSessions(w, key, themes, opts)
+	}
+}
+
+func writeRegSessions(w io.Writer, key string, sessions []namedPalette, opts encodeOptions) error {
+	fmt.Fprintln(w, "Windows Registry Editor Version 5.00")
+
+	for _, session := range sessions {
+		section := opts.sessionsKey(key) + `\` + puttyEscape(session.name)
+
+		if opts.clean {
+			fmt.Fprintln(w, "")
+			fmt.Fprintf(w, "[-%s]\n", section)
+		}
+
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "[%s]\n", section)
+
+		settings, err := opts.sessionSettings(session.name)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range sessionRegLines(session.palette, settings) {
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	// regedit exports end with a blank line
+	fmt.Fprintln(w, "")
+
+	return nil
+}
+
+// writeRegHeader starts a .reg file setting values of the given key
+// under HKEY_CURRENT_USER.
+func writeRegHeader(w io.Writer, key string) {
+	fmt.Fprintln(w, "Windows Registry Editor Version 5.00")
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "[HKEY_CURRENT_USER\\%s]\n", key)
+}
+
+// writeConsoleReg writes a .reg file with the color table of the Windows
+// console used by cmd.exe and PowerShell, in its own order. Consoles
+// started from a shortcut or with a given title read their own subkey,
+// named after the title with "\\" written as "_", so the session name
+// picks the subkey, and "default" sets the defaults for all of them.
+func writeConsoleReg(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	key := "Console"
+	if !strings.EqualFold(sname, "default") {
+		key += `\` + strings.ReplaceAll(sname, `\`, "_")
+	}
+
+	writeRegHeader(w, key)
+
+	entries := consoleColorTable(p)
+	for i, c := range entries {
+		fmt.Fprintf(w, "\"ColorTable%02d\"=dword:%s\n", i, bgrDword(c))
+	}
+
+	// the low nibble picks the text color and the next one the background
+	fg, bg := nearestColor(p.keys["foreground"], entries), nearestColor(p.keys["background"], entries)
+	fmt.Fprintf(w, "\"ScreenColors\"=dword:%08x\n", bg<<4|fg)
+
+	fmt.Fprintln(w, "")
+
+	return nil
+}
+
+// warnDropped warns about the keys found in the input that a session of
+// the given PuTTY fork has no place for.
+func warnDropped(p palette, vendor string) {
+	dropped := make([]string, 0, len(unsupportedKeys))
+	for _, keyName := range unsupportedKeys {
+		if _, found := p.keys[keyName]; found {
+			dropped = append(dropped, keyName)
+		}
+	}
+
+	if len(dropped) != 0 {
+		warnf("%s sessions have no selection colors, they show the selection in reverse video, so these keys were dropped: %s", vendor, strings.Join(dropped, ", "))
+	}
+}
+
+// themeColors returns the names and keys of the 19 colors every theme
+// has: foreground, background, cursor and the 16 palette entries.
+func themeColors() [][2]string {
+	names := [][2]string{{"foreground", "foreground"}, {"background", "background"}, {"cursor", "cursorColor"}}
+	for _, key := range paletteKeys() {
+		names = append(names, [2]string{key, key})
+	}
+
+	return names
+}
+
+// paletteKeys returns color0 through color15, in numeric order.
+func paletteKeys() []string {
+	keys := make([]string, 0, 16)
+	for i := 0; i < 16; i++ {
+		keys = append(keys, fmt.Sprintf("color%d", i))
+	}
+
+	return keys
+}
+
+// writePortableSession writes the session as a file for the Sessions
+// directory of KiTTY in portable mode, where each value is written as
+// "Name\value\".
+func writePortableSession(w io.Writer, sname string, p palette, opts encodeOptions) error {
+	warnDropped(p, "KiTTY")
+
+	settings, err := opts.sessionSettings(sname)
+	if err != nil {
+		return err
+	}
+
+	for _, color := range p.slots {
+		fmt.Fprintf(w, "%s\\%s\\\n", color.name, color.getRGB())
+	}
+
+	for _, setting := range settings {
+		switch {
+		case setting.isText && strings.ContainsAny(setting.text, "\\\r\n"):
+			return fmt.Errorf("the value of %s can't have backslashes or line breaks in a portable session file", setting.name)
+		case setting.isText:
+			fmt.Fprintf(w, "%s\\%s\\\n", setting.name, setting.text)
+		default:
+			fmt.Fprintf(w, "%s\\%d\\\n", setting.name, setting.value)
+		}
+	}
+
+	fmt.Fprintln(w, "")
+
+	return nil
+}
+
+// maxSessionKeyLength is the longest registry key name Windows allows.
+const maxSessionKeyLength = 255
+
+// isSessionFormat reports whether the format writes PuTTY or KiTTY
+// sessions, whose names are stored escaped as registry keys or file names.
+func (f outputFormat) isSessionFormat() bool {
+	return sessionKeys[f.name] != "" || f.name == "kitty-portable"
+}
+
+// validateRegistryPath makes sure the path is a usable registry key for
+// --registry-path, relative to the root key.
+func validateRegistryPath(path string) error {
+	switch {
+	case path == "":
+		return errors.New("registry path is empty")
+	case strings.HasPrefix(path, `\`) || strings.HasSuffix(path, `\`):
+		return fmt.Errorf("registry path %q can't start or end with a backslash", path)
+	case strings.HasPrefix(strings.ToUpper(path), "HKEY_"):
+		return fmt.Errorf("registry path %q is relative to the root key, pick the root with --registry-root instead", path)
+	case strings.ContainsFunc(path, unicode.IsControl) || strings.ContainsAny(path, `[]"`):
+		return fmt.Errorf("registry path %q has characters that can't be used in a .reg file key, like control characters, brackets or quotes", path)
+	}
+
+	for _, name := range strings.Split(path, `\`) {
+		switch {
+		case strings.TrimSpace(name) == "":
+			return fmt.Errorf("registry path %q has an empty key name", path)
+		case len(name) > maxSessionKeyLength:
+			return fmt.Errorf("registry path %q has a key name longer than the %d characters allowed", path, maxSessionKeyLength)
+		}
+	}
+
+	return nil
+}
+
+// validateSessionName makes sure PuTTY and KiTTY can store the session
+// under its escaped name, warning about surrounding whitespace, which is
+// kept as part of the name but rarely meant to be.
+func validateSessionName(sname string) error {
+	escaped := puttyEscape(sname)
+
+	switch {
+	case strings.TrimSpace(sname) == "":
+		return errors.New("session name is empty")
+	case strings.Trim(sname, ".") == "":
+		return fmt.Errorf("session name %q is made of dots only", sname)
+	case strings.ContainsFunc(sname, unicode.IsControl):
+		return fmt.Errorf("session name %q has control characters, which would be stored as %q", sname, escaped)
+	case len(escaped) > maxSessionKeyLength:
+		return fmt.Errorf("session name %q is too long, once escaped as %q it's %d characters and registry keys can have up to %d", sname, escaped, len(escaped), maxSessionKeyLength)
+	}
+
+	if strings.TrimSpace(sname) != sname {
+		warnf("session name %q starts or ends with whitespace, which is kept as part of the name (stored as %q)", sname, escaped)
+	}
+
+	return nil
+}
+
+// puttyEscape escapes a session name the way the mungestr function of
+// PuTTY's winstore.c does for registry keys, and KiTTY does for the names
+// of portable session files: spaces, backslashes, "*", "?", "%", control
+// characters, each byte of non-ASCII characters, and a leading ".", are
+// written as "%XX". Everything else, "/" and "+" included, is kept.
+func puttyEscape(sname string) string {
+	var sb strings.Builder
+	for i := 0; i < len(sname); i++ {
+		c := sname[i]
+		if c == ' ' || c == '\\' || c == '*' || c == '?' || c == '%' || c < ' ' || c > '~' || (c == '.' && i == 0) {
+			fmt.Fprintf(&sb, "%%%02X", c)
+			continue
+		}
+
+		sb.WriteByte(c)
+	}
+
+	return sb.String()
+}
+
+// portableFileName returns the name of the portable session file, escaped
+// like a registry key but with "/" written as "%2F" too, as a file name
+// can't hold one. As a leading "." is escaped as well, no session name can
+// point outside of the Sessions directory.
+func portableFileName(sname string) string {
+	return strings.ReplaceAll(puttyEscape(sname), "/", "%2F")
+}
+
+// validateFileName makes sure a session name can be used as the name of
+// its output file in --out-dir, without pointing to another directory.
+func validateFileName(name string) error {
+	switch {
+	case strings.ContainsAny(name, `/\`):
+		return fmt.Errorf("output file name %q can't have path separators, pick another session name or use --output", name)
+	case name == "." || name == "..":
+		return fmt.Errorf("output file name %q isn't a file name, pick another session name or use --output", name)
+	}
+
+	return nil
+}
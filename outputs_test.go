@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPuttyEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"plain", "plain"},
+		{"my session", "my%20session"},
+		{"100%", "100%25"},
+		{`back\slash`, "back%5Cslash"},
+		{"a/b", "a/b"},
+		{"a+b*c?", "a+b%2Ac%3F"},
+		{".hidden", "%2Ehidden"},
+		{"not.hidden.", "not.hidden."},
+		{"tab\there", "tab%09here"},
+		{"~tilde", "~tilde"},
+		{"日本", "%E6%97%A5%E6%9C%AC"},
+		{"🎨", "%F0%9F%8E%A8"},
+	}
+
+	for _, tt := range tests {
+		if got := puttyEscape(tt.name); got != tt.want {
+			t.Errorf("puttyEscape(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPortableFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"my session", "my%20session"},
+		{"a/b", "a%2Fb"},
+		{"a/../../pwned", "a%2F..%2F..%2Fpwned"},
+		{"../pwned", "%2E.%2Fpwned"},
+		{"..", "%2E."},
+		{`..\pwned`, "%2E.%5Cpwned"},
+		{"/etc/passwd", "%2Fetc%2Fpasswd"},
+	}
+
+	for _, tt := range tests {
+		got := portableFileName(tt.name)
+		if got != tt.want {
+			t.Errorf("portableFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+
+		if dir := filepath.Join("Sessions", got); filepath.Dir(dir) != "Sessions" {
+			t.Errorf("portableFileName(%q) = %q, which is outside of the Sessions directory", tt.name, got)
+		}
+	}
+}
+
+func TestValidateFileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"session.reg", false},
+		{"my session.css", false},
+		{"..hidden.reg", false},
+		{"a/../../pwned.css", true},
+		{`..\pwned.css`, true},
+		{"/etc/passwd", true},
+		{"..", true},
+		{".", true},
+	}
+
+	for _, tt := range tests {
+		if err := validateFileName(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("validateFileName(%q) = %v, want an error: %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateSessionName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr string
+	}{
+		{"work laptop", ""},
+		{"a/../../pwned", ""},
+		{"", "empty"},
+		{"   ", "empty"},
+		{"...", "dots only"},
+		{"bad\x07name", "control characters"},
+		{strings.Repeat("x", maxSessionKeyLength+1), "too long"},
+		{strings.Repeat("%", maxSessionKeyLength/3+1), "too long"},
+	}
+
+	for _, tt := range tests {
+		err := validateSessionName(tt.name)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validateSessionName(%q) = %v, want no error", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validateSessionName(%q) = %v, want an error about %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPortableSession(t *testing.T) {
+	p := builtinTestPalette(t, "tomorrow-night")
+	opts := encodeOptions{settings: []sessionSetting{
+		{name: "BoldAsColour", value: 1},
+		{name: "HostName", text: "example.com", isText: true},
+	}}
+
+	var b bytes.Buffer
+	if err := writePortableSession(&b, "Tomorrow Night", p, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// KiTTY saves the session in a file named after it, escaped
+	name := portableFileName("Tomorrow Night")
+	checkGolden(t, name, b.Bytes())
+
+	// read it back the way KiTTY does, a "name\value\" line per setting
+	values := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(b.Bytes()))
+	for sc.Scan() {
+		if sc.Text() == "" {
+			continue
+		}
+
+		fields := strings.Split(sc.Text(), `\`)
+		if len(fields) != 3 || fields[2] != "" {
+			t.Fatalf("line %q isn't a name\\value\\ pair", sc.Text())
+		}
+
+		values[fields[0]] = fields[1]
+	}
+
+	for _, color := range p.slots {
+		if got := values[color.name]; got != color.getRGB() {
+			t.Errorf("%s = %q, want %q", color.name, got, color.getRGB())
+		}
+	}
+
+	if values["BoldAsColour"] != "1" || values["HostName"] != "example.com" {
+		t.Errorf("settings read back as BoldAsColour %q and HostName %q, want 1 and example.com", values["BoldAsColour"], values["HostName"])
+	}
+
+	opts.settings[1].text = `C:\Users`
+	if err := writePortableSession(&bytes.Buffer{}, "Tomorrow Night", p, opts); err == nil {
+		t.Error("writePortableSession() succeeded, want an error for the backslash in HostName")
+	}
+}
+
+func TestWriteConsoleReg(t *testing.T) {
+	p := builtinTestPalette(t, "solarized-light")
+
+	var b bytes.Buffer
+	if err := writeConsoleReg(&b, `%SystemRoot%\System32\cmd.exe`, p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "solarized-light.console.reg", b.Bytes())
+
+	// the foreground is color11 and the background color15, which the
+	// console keeps at 14 and 15
+	if !strings.Contains(b.String(), `"ScreenColors"=dword:000000fe`) {
+		t.Errorf("ScreenColors isn't background 15 and text 14:\n%s", b.Bytes())
+	}
+
+	b.Reset()
+	if err := writeConsoleReg(&b, "Default", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), "\n[HKEY_CURRENT_USER\\Console]\n") {
+		t.Errorf("the default session isn't written to the Console key:\n%s", b.Bytes())
+	}
+}
+
+func TestEncodeUTF16(t *testing.T) {
+	tests := []struct {
+		text string
+		want []byte
+	}{
+		{"", []byte{0xff, 0xfe}},
+		{"a\n", []byte{0xff, 0xfe, 'a', 0, '\r', 0, '\n', 0}},
+		{"a\r\n", []byte{0xff, 0xfe, 'a', 0, '\r', 0, '\n', 0}},
+		{"é", []byte{0xff, 0xfe, 0xe9, 0}},
+		{"🎨", []byte{0xff, 0xfe, 0x3c, 0xd8, 0xa8, 0xdf}},
+	}
+
+	for _, tt := range tests {
+		if got := encodeUTF16(tt.text); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeUTF16(%q) = % x, want % x", tt.text, got, tt.want)
+		}
+	}
+
+	for _, text := range []string{"[HKEY_CURRENT_USER\\Software\\日本]\r\n", "🎨 Sessions\r\n"} {
+		if got := string(decodeUTF16(encodeUTF16(text))); got != text {
+			t.Errorf("decodeUTF16(encodeUTF16(%q)) = %q", text, got)
+		}
+	}
+}
+
+func TestRegeditCompatible(t *testing.T) {
+	// the session as regedit exports it, in UTF-16 with CRLF line endings
+	path := filepath.Join("testdata", "tomorrow-night.regedit.reg")
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := findOutputFormat("kitty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.opts.utf16 = true
+
+	var b bytes.Buffer
+	if err := f.encode(&b, "Tomorrow Night", builtinTestPalette(t, "tomorrow-night")); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("output differs from %s\ngot:\n% x\nwant:\n% x", path, b.Bytes(), want)
+	}
+
+	// and it reads back like the UTF-8 output
+	lines, err := scanLines(path, bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if format, err := findInputFormat("", lines); err != nil || format.name != "reg" {
+		t.Errorf("findInputFormat() = %q, %v, want the reg format", format.name, err)
+	}
+}
+
+func TestCleanSessions(t *testing.T) {
+	tmpl, err := readSessionTemplate(filepath.Join("testdata", "session-template.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := findOutputFormat("kitty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := builtinTestPalette(t, "gruvbox-dark")
+
+	tests := []struct {
+		golden string
+		opts   encodeOptions
+	}{
+		{"clean.reg", encodeOptions{clean: true, template: tmpl, sessions: []string{"web1", "web2"}}},
+		{"clean-colors-only.reg", encodeOptions{clean: true}},
+	}
+
+	for _, tt := range tests {
+		f.opts = tt.opts
+
+		var b bytes.Buffer
+		if err := f.encode(&b, "gruvbox", p); err != nil {
+			t.Fatal(err)
+		}
+
+		checkGolden(t, tt.golden, b.Bytes())
+
+		// every session is deleted right before it's written again
+		var deleted string
+		for _, line := range strings.Split(b.String(), "\n") {
+			switch {
+			case strings.HasPrefix(line, "[-"):
+				deleted = strings.TrimPrefix(line, "[-")
+			case strings.HasPrefix(line, "["):
+				if deleted != strings.TrimPrefix(line, "[") {
+					t.Errorf("%s: section %s isn't deleted first", tt.golden, line)
+				}
+
+				deleted = ""
+			}
+		}
+	}
+}
+
+func TestColourOrder(t *testing.T) {
+	f, err := findOutputFormat("kitty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.encode(&b, "Tomorrow Night", readTestPalette(t, "mixed-case.Xresources")); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "mixed-case.reg", b.Bytes())
+
+	var names []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, `"Colour`) {
+			names = append(names, line[1:strings.Index(line[1:], `"`)+1])
+		}
+	}
+
+	if len(names) != 22 {
+		t.Fatalf("got %d colors, want Colour0 through Colour21", len(names))
+	}
+
+	for i, name := range names {
+		if want := fmt.Sprintf("Colour%d", i); name != want {
+			t.Errorf("line %d sets %s, want %s", i+1, name, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// pngGlyphs is a 3x5 pixel font with just the characters needed to label
+// the swatches with their hex values, one string per row.
+var pngGlyphs = map[rune][5]string{
+	'#': {"# #", "###", "# #", "###", "# #"},
+	'0': {"###", "# #", "# #", "# #", "###"},
+	'1': {" # ", "## ", " # ", " # ", "###"},
+	'2': {"###", "  #", "###", "#  ", "###"},
+	'3': {"###", "  #", " ##", "  #", "###"},
+	'4': {"# #", "# #", "###", "  #", "  #"},
+	'5': {"###", "#  ", "###", "  #", "###"},
+	'6': {"###", "#  ", "###", "# #", "###"},
+	'7': {"###", "  #", "  #", "  #", "  #"},
+	'8': {"###", "# #", "###", "# #", "###"},
+	'9': {"###", "# #", "###", "  #", "###"},
+	'a': {" # ", "# #", "###", "# #", "# #"},
+	'b': {"## ", "# #", "## ", "# #", "## "},
+	'c': {"###", "#  ", "#  ", "#  ", "###"},
+	'd': {"## ", "# #", "# #", "# #", "## "},
+	'e': {"###", "#  ", "## ", "#  ", "###"},
+	'f': {"###", "#  ", "## ", "#  ", "#  "},
+}
+
+// drawPalette draws the palette over its background: a strip of the
+// foreground, a thinner one of the cursor color, and the 16 palette colors
+// as squares labelled with their hex values, in two rows of eight. All
+// sizes are multiplied by scale.
+func drawPalette(p palette, scale int) image.Image {
+	square, gap := 64*scale, 8*scale
+	width := 8*square + 9*gap
+	height := gap + square/2 + gap + square/4 + gap + 2*(square+gap)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill := func(r image.Rectangle, c color.RGBA) {
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+
+	fill(img.Bounds(), p.keys["background"])
+
+	y := gap
+	fill(image.Rect(gap, y, width-gap, y+square/2), p.keys["foreground"])
+	y += square/2 + gap
+	fill(image.Rect(gap, y, width-gap, y+square/4), p.keys["cursorColor"])
+	y += square/4 + gap
+
+	for i, key := range paletteKeys() {
+		c := p.keys[key]
+		x0, y0 := gap+(i%8)*(square+gap), y+(i/8)*(square+gap)
+		fill(image.Rect(x0, y0, x0+square, y0+square), c)
+
+		label := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		if isLight(c) {
+			label = color.RGBA{A: 0xff}
+		}
+
+		drawText(img, hexColor(c), x0+2*scale, y0+square-7*scale, scale, label)
+	}
+
+	return img
+}
+
+// drawText writes the text with pngGlyphs, its top left corner at x and
+// y. Each font pixel is a scale sized square, so the hex values of the
+// swatches fit their width.
+func drawText(img *image.RGBA, text string, x, y, scale int, c color.RGBA) {
+	for _, r := range text {
+		for row, line := range pngGlyphs[r] {
+			for col, pixel := range line {
+				if pixel != '#' {
+					continue
+				}
+
+				px, py := x+col*scale, y+row*scale
+				draw.Draw(img, image.Rect(px, py, px+scale, py+scale), image.NewUniform(c), image.Point{}, draw.Src)
+			}
+		}
+
+		x += 4 * scale
+	}
+}
+
+// writePNG writes a PNG image of the palette, drawn by drawPalette.
+func writePNG(w io.Writer, _ string, p palette, opts encodeOptions) error {
+	if err := png.Encode(w, drawPalette(p, opts.pngScale)); err != nil {
+		return fmt.Errorf("unable to encode the image: %s", err.Error())
+	}
+
+	return nil
+}
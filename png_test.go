@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// pixelHash returns the SHA-256 of the pixels of the image.
+func pixelHash(img image.Image) string {
+	h := sha256.New()
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			h.Write([]byte{c.R, c.G, c.B, c.A})
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func TestDrawPalette(t *testing.T) {
+	p := builtinTestPalette(t, "nord")
+
+	tests := []struct {
+		scale int
+		size  image.Point
+		hash  string
+	}{
+		{1, image.Pt(584, 216), "a3c792c6fddfe6884ee1ca1cd1ff1a43bcbba53f0299d60ec861b68a85471394"},
+		{2, image.Pt(1168, 432), "9fd18c0e08bc24861af9e93341fa647a13f135eca932434dd7af26483df2d6a1"},
+	}
+
+	for _, tt := range tests {
+		img := drawPalette(p, tt.scale)
+		if got := img.Bounds().Size(); got != tt.size {
+			t.Errorf("scale %d: image is %v, want %v", tt.scale, got, tt.size)
+		}
+
+		if got := pixelHash(img); got != tt.hash {
+			t.Errorf("scale %d: pixels hash to %s, want %s", tt.scale, got, tt.hash)
+		}
+	}
+
+	// the background around everything, the foreground strip on top and
+	// the first swatch below the cursor strip
+	img := drawPalette(p, 1)
+	for _, tt := range []struct {
+		x, y int
+		key  string
+	}{
+		{0, 0, "background"},
+		{8, 8, "foreground"},
+		{8, 48, "cursorColor"},
+		{8, 72, "color0"},
+		{80, 144, "color9"},
+	} {
+		if got := color.RGBAModel.Convert(img.At(tt.x, tt.y)); got != p.keys[tt.key] {
+			t.Errorf("pixel at %d,%d = %v, want the %s %v", tt.x, tt.y, got, tt.key, p.keys[tt.key])
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	p := builtinTestPalette(t, "nord")
+
+	var b bytes.Buffer
+	if err := writePNG(&b, "Nord", p, encodeOptions{pngScale: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := pixelHash(img), pixelHash(drawPalette(p, 1)); got != want {
+		t.Errorf("decoded pixels hash to %s, want %s as drawn", got, want)
+	}
+}
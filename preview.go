@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// writePreview prints the theme for a quick look: with colors, each
+// palette entry as a block along with sample text in the foreground and
+// cursor colors, using 24-bit SGR sequences, and without them, a table of
+// the colors and their hex values.
+func writePreview(w io.Writer, sname string, p palette, colors bool) {
+	if !colors {
+		fmt.Fprintln(w, sname)
+		for _, name := range themeColors() {
+			fmt.Fprintf(w, "  %-11s %s\n", name[0], hexColor(p.keys[name[1]]))
+		}
+
+		return
+	}
+
+	bg := func(c color.RGBA) string {
+		return fmt.Sprintf("\033[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+
+	fg := func(c color.RGBA) string {
+		return fmt.Sprintf("\033[38;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+
+	fmt.Fprintln(w, sname)
+
+	for i := 0; i < 8; i++ {
+		for _, n := range []int{i, i + 8} {
+			c := p.keys[fmt.Sprintf("color%d", n)]
+			fmt.Fprintf(w, "  %s      \033[0m %2d %s", bg(c), n, hexColor(c))
+		}
+
+		fmt.Fprintln(w, "")
+	}
+
+	back := bg(p.keys["background"])
+	fmt.Fprintf(w, "  %s%s foreground %s on background %s \033[0m\n", back, fg(p.keys["foreground"]), hexColor(p.keys["foreground"]), hexColor(p.keys["background"]))
+	fmt.Fprintf(w, "  %s%s cursor %s %s \033[0m\n", back, fg(p.keys["foreground"]), hexColor(p.keys["cursorColor"]), bg(p.keys["cursorColor"]))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pywalScheme is the layout of the colors.json file written by pywal to
+// ~/.cache/wal/colors.json.
+type pywalScheme struct {
+	Special map[string]string `json:"special"`
+	Colors  map[string]string `json:"colors"`
+}
+
+// pywalFields maps the pywal fields to the Xresources keys they set.
+var pywalFields = map[string]string{
+	"special.foreground": "foreground",
+	"special.background": "background",
+	"special.cursor":     "cursorColor",
+}
+
+func init() {
+	for i := 0; i < 16; i++ {
+		pywalFields[fmt.Sprintf("colors.color%d", i)] = fmt.Sprintf("color%d", i)
+	}
+}
+
+func detectPywal(content string) bool {
+	content = strings.TrimSpace(content)
+	return strings.HasPrefix(content, "{") && strings.Contains(content, `"special"`) && strings.Contains(content, `"colors"`)
+}
+
+// parsePywal reads a pywal colors.json file. Since the file layout is
+// fixed, missing fields are reported using the pywal names.
+func parsePywal(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	var scheme pywalScheme
+	if err := json.Unmarshal([]byte(joinLines(lines)), &scheme); err != nil {
+		return nil, fmt.Errorf("invalid pywal JSON: %s", err.Error())
+	}
+
+	values := map[string]resource{}
+	missing := make([]string, 0, len(pywalFields))
+
+	for _, field := range sortedKeys(pywalFields) {
+		section, name := scheme.Special, strings.TrimPrefix(field, "special.")
+		if strings.HasPrefix(field, "colors.") {
+			section, name = scheme.Colors, strings.TrimPrefix(field, "colors.")
+		}
+
+		value, found := section[name]
+		if !found {
+			missing = append(missing, field)
+			continue
+		}
+
+		debugf("%s: using %s: %s", lines[0].file, field, value)
+		values[pywalFields[field]] = resource{value: value, source: fieldSource(lines, field)}
+	}
+
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("the following pywal fields weren't found: %s", strings.Join(missing, ", "))
+	}
+
+	return values, nil
+}
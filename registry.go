@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	reRegSection = regexp.MustCompile(`^\[(-?)(HKEY_[^\]]+)\]$`)
+	reRegValue   = regexp.MustCompile(`^"((?:[^"\\]|\\.)*)"=(.*)$`)
+)
+
+// regSection is a registry key from a .reg file along with its values.
+type regSection struct {
+	path   string
+	values map[string]regValue
+	source sourceLine
+}
+
+type regValue struct {
+	data   string
+	source sourceLine
+}
+
+// sessionName returns the name of the PuTTY or KiTTY session stored in
+// the section, which is the escaped last component of its path.
+func (rs regSection) sessionName() string {
+	name := rs.path[strings.LastIndex(rs.path, `\`)+1:]
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+
+	return name
+}
+
+func detectReg(content string) bool {
+	content = strings.TrimSpace(content)
+	return strings.HasPrefix(content, "Windows Registry Editor") || strings.HasPrefix(content, "REGEDIT4")
+}
+
+// parseRegSections reads the sections of a .reg file, keeping only the
+// string values since that's how session colors are stored.
+func parseRegSections(lines []sourceLine) []regSection {
+	var sections []regSection
+
+	for _, line := range lines {
+		text := strings.TrimSpace(line.text)
+
+		if m := reRegSection.FindStringSubmatch(text); m != nil {
+			if m[1] == "" {
+				sections = append(sections, regSection{path: m[2], values: map[string]regValue{}, source: line})
+			}
+
+			continue
+		}
+
+		m := reRegValue.FindStringSubmatch(text)
+		if m == nil || len(sections) == 0 || !strings.HasPrefix(m[2], `"`) {
+			continue
+		}
+
+		data, err := strconv.Unquote(m[2])
+		if err != nil {
+			continue
+		}
+
+		sections[len(sections)-1].values[m[1]] = regValue{data: data, source: line}
+	}
+
+	return sections
+}
+
+// regSessionSection picks the section of the .reg file holding session
+// colors, selected by session name when the file has more than one.
+func regSessionSection(lines []sourceLine, opts decodeOptions) (regSection, error) {
+	var (
+		candidates []regSection
+		names      []string
+	)
+
+	for _, section := range parseRegSections(lines) {
+		if _, found := section.values[colorPrefix+"0"]; !found {
+			continue
+		}
+
+		if opts.scheme != "" && section.sessionName() == opts.scheme {
+			return section, nil
+		}
+
+		candidates = append(candidates, section)
+		names = append(names, section.sessionName())
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return regSection{}, fmt.Errorf("no session with %s values found in registry file", colorPrefix)
+	case opts.scheme != "":
+		return regSection{}, fmt.Errorf("no session named %q in registry file, available sessions: %s", opts.scheme, strings.Join(names, ", "))
+	case len(candidates) > 1:
+		return regSection{}, fmt.Errorf("registry file has %d sessions, pick one with --scheme: %s", len(candidates), strings.Join(names, ", "))
+	}
+
+	return candidates[0], nil
+}
+
+// parseReg reads the colors of a PuTTY or KiTTY session back from a .reg
+// file, such as one exported with regedit, reversing nameReplacements.
+// The cursor text and bold colors are only kept when they differ from the
+// colors they are normally copied from.
+func parseReg(lines []sourceLine, opts decodeOptions) (map[string]resource, error) {
+	section, err := regSessionSection(lines, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]resource{}
+	var missing []int
+
+	for _, keys := range []map[string][]int{nameReplacements, optionalReplacements} {
+		for key, slots := range keys {
+			name := fmt.Sprintf("%s%d", colorPrefix, slots[0])
+			v, found := section.values[name]
+			if !found {
+				if _, required := nameReplacements[key]; required {
+					missing = append(missing, slots[0])
+				}
+
+				continue
+			}
+
+			debugf("%s: using %s: %s", v.source, name, v.data)
+			values[key] = resource{value: v.data, source: v.source}
+		}
+	}
+
+	for key, from := range map[string]string{"colorBD": "foreground", "cursorColor2": "cursorColor"} {
+		if res, found := values[key]; found && res.value == values[from].value {
+			delete(values, key)
+		}
+	}
+
+	if len(missing) != 0 {
+		sort.Ints(missing)
+
+		names := make([]string, 0, len(missing))
+		for _, slot := range missing {
+			names = append(names, fmt.Sprintf("%s%d", colorPrefix, slot))
+		}
+
+		warnf("session %q only has partial color data, the following values are absent: %s", section.sessionName(), strings.Join(names, ", "))
+	}
+
+	return values, nil
+}
+
+// regName returns the name of the session read from the .reg file.
+func regName(lines []sourceLine, opts decodeOptions) string {
+	section, err := regSessionSection(lines, opts)
+	if err != nil {
+		return ""
+	}
+
+	return section.sessionName()
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPartialSession(t *testing.T) {
+	var values map[string]resource
+	stderr := captureStderr(t, func() {
+		values = readTestValues(t, "partial-session.reg")
+	})
+
+	if want := "the following values are absent: Colour4, Colour10, Colour14\n"; !strings.HasSuffix(stderr, want) {
+		t.Errorf("warned %q, want the absent values in numeric order", stderr)
+	}
+
+	p, err := convert(values)
+	if err != nil {
+		t.Fatalf("convert() = %s, want a partial theme", err)
+	}
+
+	var b bytes.Buffer
+	if err := writeXresources(&b, "Tomorrow Night", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "partial-session.Xresources", b.Bytes())
+
+	for _, key := range []string{"cursorColor", "color2", "color4"} {
+		if _, found := p.keys[key]; found {
+			t.Errorf("%s is set, want it left out with its Colour value absent", key)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// canWriteRegistry reports whether --write-registry is supported.
+const canWriteRegistry = false
+
+func writeRegistry(io.Writer, string, string, string, palette, []sessionSetting, bool) error {
+	return errors.New("--write-registry is only supported on Windows")
+}
+
+func diffRegistry(io.Writer, string, string, string, palette) (bool, error) {
+	return false, errors.New("--diff-registry is only supported on Windows")
+}
@@ -0,0 +1,276 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"syscall"
+	"unsafe"
+)
+
+// canWriteRegistry reports whether --write-registry is supported.
+const canWriteRegistry = true
+
+var (
+	advapi32            = syscall.NewLazyDLL("advapi32.dll")
+	procRegCreateKeyExW = advapi32.NewProc("RegCreateKeyExW")
+	procRegSetValueExW  = advapi32.NewProc("RegSetValueExW")
+)
+
+// registryRootHandles are the handles of the registryRoots.
+var registryRootHandles = map[string]syscall.Handle{
+	"HKEY_CURRENT_USER":  syscall.HKEY_CURRENT_USER,
+	"HKEY_LOCAL_MACHINE": syscall.HKEY_LOCAL_MACHINE,
+}
+
+// writeRegistry sets the session colors and settings straight into the
+// registry, under the given key of the root key, printing the values that
+// changed to w. The session key is only created when create is set, so a
+// typo in the session name doesn't create a new session.
+func writeRegistry(w io.Writer, root, key, sname string, p palette, settings []sessionSetting, create bool) error {
+	rootHandle, found := registryRootHandles[root]
+	if !found {
+		return fmt.Errorf("unsupported registry root key %q", root)
+	}
+
+	path, err := syscall.UTF16PtrFromString(key + `\` + puttyEscape(sname))
+	if err != nil {
+		return fmt.Errorf("invalid session name %q: %s", sname, err.Error())
+	}
+
+	var h syscall.Handle
+	err = syscall.RegOpenKeyEx(rootHandle, path, 0, syscall.KEY_QUERY_VALUE|syscall.KEY_SET_VALUE, &h)
+
+	switch {
+	case err == syscall.ERROR_FILE_NOT_FOUND && !create:
+		return fmt.Errorf("there's no session %q in the registry, pass --create to create it", sname)
+	case err == syscall.ERROR_FILE_NOT_FOUND:
+		if err := regCreateKey(rootHandle, path, &h); err != nil {
+			return fmt.Errorf("can't create the registry key for session %q: %s", sname, err.Error())
+		}
+
+		fmt.Fprintf(w, "created session %q\n", sname)
+	case err != nil:
+		return fmt.Errorf("can't open the registry key for session %q: %s", sname, err.Error())
+	}
+
+	defer syscall.RegCloseKey(h)
+
+	changed := 0
+	for _, c := range p.slots {
+		value := c.getRGB()
+
+		old := regGetString(h, c.name)
+		if old == value {
+			continue
+		}
+
+		if err := regSetString(h, c.name, value); err != nil {
+			return fmt.Errorf("can't set %s for session %q: %s", c.name, sname, err.Error())
+		}
+
+		if old == "" {
+			fmt.Fprintf(w, "%s: set to %s\n", c.name, value)
+		} else {
+			fmt.Fprintf(w, "%s: changed from %s to %s\n", c.name, old, value)
+		}
+
+		changed++
+	}
+
+	for _, setting := range settings {
+		if setting.isText {
+			old := regGetString(h, setting.name)
+			if old == setting.text {
+				continue
+			}
+
+			if err := regSetString(h, setting.name, setting.text); err != nil {
+				return fmt.Errorf("can't set %s for session %q: %s", setting.name, sname, err.Error())
+			}
+
+			if old == "" {
+				fmt.Fprintf(w, "%s: set to %q\n", setting.name, setting.text)
+			} else {
+				fmt.Fprintf(w, "%s: changed from %q to %q\n", setting.name, old, setting.text)
+			}
+
+			changed++
+			continue
+		}
+
+		old, found := regGetDword(h, setting.name)
+		if found && old == setting.value {
+			continue
+		}
+
+		if err := regSetDword(h, setting.name, setting.value); err != nil {
+			return fmt.Errorf("can't set %s for session %q: %s", setting.name, sname, err.Error())
+		}
+
+		if !found {
+			fmt.Fprintf(w, "%s: set to %d\n", setting.name, setting.value)
+		} else {
+			fmt.Fprintf(w, "%s: changed from %d to %d\n", setting.name, old, setting.value)
+		}
+
+		changed++
+	}
+
+	if changed == 0 {
+		fmt.Fprintf(w, "session %q already has these colors\n", sname)
+	}
+
+	return nil
+}
+
+// diffRegistry prints how the colors of the session in the registry, under
+// the given key of the root key, differ from the palette, summing up the
+// ones that are the same, and reports whether any of them differ. When
+// the session doesn't exist, all the colors are reported as new.
+func diffRegistry(w io.Writer, root, key, sname string, p palette) (bool, error) {
+	rootHandle, found := registryRootHandles[root]
+	if !found {
+		return false, fmt.Errorf("unsupported registry root key %q", root)
+	}
+
+	path, err := syscall.UTF16PtrFromString(key + `\` + puttyEscape(sname))
+	if err != nil {
+		return false, fmt.Errorf("invalid session name %q: %s", sname, err.Error())
+	}
+
+	var h syscall.Handle
+	err = syscall.RegOpenKeyEx(rootHandle, path, 0, syscall.KEY_QUERY_VALUE, &h)
+
+	switch {
+	case err == syscall.ERROR_FILE_NOT_FOUND:
+		fmt.Fprintf(w, "there's no session %q in the registry\n", sname)
+	case err != nil:
+		return false, fmt.Errorf("can't open the registry key for session %q: %s", sname, err.Error())
+	default:
+		defer syscall.RegCloseKey(h)
+	}
+
+	unchanged := 0
+	for _, c := range p.slots {
+		value := c.getRGB()
+
+		old := ""
+		if h != 0 {
+			old = regGetString(h, c.name)
+		}
+
+		switch old {
+		case value:
+			unchanged++
+		case "":
+			fmt.Fprintf(w, "%s: new %s\n", c.name, value)
+		default:
+			fmt.Fprintf(w, "%s: %s -> %s\n", c.name, old, value)
+		}
+	}
+
+	if unchanged == len(p.slots) {
+		fmt.Fprintf(w, "session %q already has these colors\n", sname)
+		return false, nil
+	}
+
+	if unchanged > 0 {
+		fmt.Fprintf(w, "%d other colors unchanged\n", unchanged)
+	}
+
+	return true, nil
+}
+
+func regCreateKey(root syscall.Handle, path *uint16, h *syscall.Handle) error {
+	r, _, _ := procRegCreateKeyExW.Call(
+		uintptr(root), uintptr(unsafe.Pointer(path)), 0, 0, 0,
+		uintptr(syscall.KEY_QUERY_VALUE|syscall.KEY_SET_VALUE), 0, uintptr(unsafe.Pointer(h)), 0,
+	)
+
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+
+	return nil
+}
+
+// regGetString returns the string value with the given name, or an empty
+// string when it's unset or not a string.
+func regGetString(h syscall.Handle, name string) string {
+	namep, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return ""
+	}
+
+	var typ, size uint32
+	if err := syscall.RegQueryValueEx(h, namep, nil, &typ, nil, &size); err != nil || typ != syscall.REG_SZ || size == 0 {
+		return ""
+	}
+
+	buf := make([]uint16, (size+1)/2)
+	if err := syscall.RegQueryValueEx(h, namep, nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &size); err != nil {
+		return ""
+	}
+
+	return syscall.UTF16ToString(buf)
+}
+
+// regGetDword returns the dword value with the given name, reporting
+// whether it's set.
+func regGetDword(h syscall.Handle, name string) (uint32, bool) {
+	namep, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, false
+	}
+
+	var typ, value uint32
+	size := uint32(4)
+	if err := syscall.RegQueryValueEx(h, namep, nil, &typ, (*byte)(unsafe.Pointer(&value)), &size); err != nil || typ != syscall.REG_DWORD {
+		return 0, false
+	}
+
+	return value, true
+}
+
+func regSetDword(h syscall.Handle, name string, value uint32) error {
+	namep, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	r, _, _ := procRegSetValueExW.Call(
+		uintptr(h), uintptr(unsafe.Pointer(namep)), 0, uintptr(syscall.REG_DWORD),
+		uintptr(unsafe.Pointer(&value)), 4,
+	)
+
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+
+	return nil
+}
+
+func regSetString(h syscall.Handle, name, value string) error {
+	namep, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := syscall.UTF16FromString(value)
+	if err != nil {
+		return err
+	}
+
+	r, _, _ := procRegSetValueExW.Call(
+		uintptr(h), uintptr(unsafe.Pointer(namep)), 0, uintptr(syscall.REG_SZ),
+		uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)*2),
+	)
+
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var reRxvtArgs = regexp.MustCompile(`(^|\s)(-fg|-bg|-cr|--?color[0-9]+)\s`)
+
+// rxvtOptions maps the short urxvt command-line options to the resources
+// they set.
+var rxvtOptions = map[string]string{
+	"-fg": "foreground",
+	"-bg": "background",
+	"-cr": "cursorColor",
+	"-pr": "pointerColor",
+}
+
+// rxvtToken is a single shell word from an urxvt command line.
+type rxvtToken struct {
+	text   string
+	source sourceLine
+}
+
+// detectRxvtArgs reports whether the content sets colors through urxvt
+// options. Xresources files are left out, even when one of their "!"
+// comments shows how to start urxvt with a few options.
+func detectRxvtArgs(content string) bool {
+	if detectXresources(content) {
+		return false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "!") && reRxvtArgs.MatchString(line+"\n") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRxvtArgs reads the colors set through urxvt command-line options,
+// such as "urxvt -fg '#c5c8c6' -bg '#1d1f21' --color0 '#282a2e'", either
+// as a pasted option string or inside a launcher script. Besides the short
+// options, any resource can be set with "-name" or "--name", like urxvt
+// itself allows.
+func parseRxvtArgs(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	tokens, err := splitRxvtArgs(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]resource{}
+
+	for i := 0; i < len(tokens)-1; i++ {
+		key, found := rxvtOptions[tokens[i].text]
+		if !found {
+			if !strings.HasPrefix(tokens[i].text, "-") {
+				continue
+			}
+
+			key = canonicalKey(strings.TrimLeft(tokens[i].text, "-"))
+			if !isKnownKey(key) {
+				continue
+			}
+		}
+
+		value := tokens[i+1]
+		i++
+
+		if key == "pointerColor" {
+			debugf("%s: ignoring %s, KiTTY has no equivalent setting", value.source, key)
+			continue
+		}
+
+		debugf("%s: using %s %s", value.source, tokens[i-1].text, value.text)
+		values[key] = resource{value: value.text, source: value.source}
+	}
+
+	return values, nil
+}
+
+// splitRxvtArgs splits the lines into shell words, handling single and
+// double quotes, backslash escapes and backslash-newline continuations.
+// Comments starting with "#" at the beginning of a word are skipped, but
+// "#rrggbb" values are kept even when they aren't quoted.
+func splitRxvtArgs(lines []sourceLine) ([]rxvtToken, error) {
+	var (
+		tokens  []rxvtToken
+		current strings.Builder
+		quote   byte
+		inWord  bool
+		start   sourceLine
+	)
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, rxvtToken{text: current.String(), source: start})
+		}
+
+		current.Reset()
+		inWord = false
+	}
+
+	for _, line := range lines {
+		text := line.text
+
+	chars:
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+
+			if !inWord {
+				start = line
+			}
+
+			switch {
+			case quote == '\'':
+				if c == '\'' {
+					quote = 0
+					continue
+				}
+
+				current.WriteByte(c)
+			case quote == '"':
+				switch {
+				case c == '"':
+					quote = 0
+				case c == '\\' && i+1 < len(text):
+					i++
+					current.WriteByte(text[i])
+				default:
+					current.WriteByte(c)
+				}
+			case c == '\'' || c == '"':
+				quote, inWord = c, true
+			case c == '\\':
+				if i+1 == len(text) {
+					// line continuation, the word keeps going on the next line
+					break chars
+				}
+
+				i++
+				current.WriteByte(text[i])
+				inWord = true
+			case c == ' ' || c == '\t' || c == ';' || c == '&' || c == '|':
+				flush()
+			case c == '#' && !inWord && !(i+1 < len(text) && isHexDigit(text[i+1])):
+				// the rest of the line is a comment, unless it looks like an
+				// unquoted color value
+				break chars
+			default:
+				current.WriteByte(c)
+				inWord = true
+			}
+		}
+
+		if quote != 0 {
+			current.WriteByte('\n')
+			continue
+		}
+
+		if !strings.HasSuffix(text, "\\") {
+			flush()
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("%s: unterminated %c quote", start, quote)
+	}
+
+	flush()
+	return tokens, nil
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
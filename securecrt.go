@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeSecureCRT writes the colors as a SecureCRT color scheme section,
+// in the CRLF line endings SecureCRT expects.
+func writeSecureCRT(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	lines := []string{
+		fmt.Sprintf("[%s]", sname),
+		fmt.Sprintf(`D:"Foreground"=%s`, bgrDword(p.keys["foreground"])),
+		fmt.Sprintf(`D:"Background"=%s`, bgrDword(p.keys["background"])),
+	}
+
+	for i, key := range paletteKeys() {
+		lines = append(lines, fmt.Sprintf(`D:"ANSI Color RGB %d"=%s`, i, bgrDword(p.keys[key])))
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\r\n")+"\r\n")
+	return err
+}
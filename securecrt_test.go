@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteSecureCRT(t *testing.T) {
+	p := builtinTestPalette(t, "tomorrow-night")
+
+	var b bytes.Buffer
+	if err := writeSecureCRT(&b, "Tomorrow Night", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "tomorrow-night.securecrt.ini", b.Bytes())
+
+	lines := strings.SplitAfter(b.String(), "\n")
+	if last := lines[len(lines)-1]; last != "" {
+		t.Errorf("the file ends with %q, want a line break", last)
+	}
+
+	for _, line := range lines[:len(lines)-1] {
+		if !strings.HasSuffix(line, "\r\n") {
+			t.Errorf("line %q doesn't end with CRLF", line)
+		}
+	}
+
+	// #cc6666 is stored blue first
+	for _, want := range []string{
+		`D:"Foreground"=00c6c8c5`,
+		`D:"Background"=00211f1d`,
+		`D:"ANSI Color RGB 1"=006666cc`,
+		`D:"ANSI Color RGB 12"=00bea281`,
+	} {
+		if !strings.Contains(b.String(), want+"\r\n") {
+			t.Errorf("the scheme has no %s line", want)
+		}
+	}
+}
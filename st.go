@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reStColorname = regexp.MustCompile(`\bcolorname\s*\[\s*\]\s*=\s*\{`)
+	reStItem      = regexp.MustCompile(`^\s*(?:\[\s*([0-9]+)\s*\]\s*=)?\s*(.*?)\s*$`)
+	reStDefault   = regexp.MustCompile(`\bunsigned\s+int\s+(defaultfg|defaultbg|defaultcs)\s*=\s*([0-9]+)\s*;`)
+)
+
+// stDefaults are the indexes st used before defaultfg, defaultbg and
+// defaultcs pointed past the 256 colors, used when the file doesn't set
+// them.
+var stDefaults = map[string]int{
+	"defaultfg": 7,
+	"defaultbg": 0,
+	"defaultcs": 256,
+}
+
+// stDefaultKeys maps the st default color variables to the Xresources keys
+// they set.
+var stDefaultKeys = map[string]string{
+	"defaultfg": "foreground",
+	"defaultbg": "background",
+	"defaultcs": "cursorColor",
+}
+
+func detectSt(content string) bool {
+	return reStColorname.MatchString(content)
+}
+
+// stripCComments replaces "/* */" and "//" comments with spaces, leaving
+// string literals and line breaks alone so offsets still point to the
+// same line.
+func stripCComments(s string) string {
+	b := []byte(s)
+
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '"' || b[i] == '\'':
+			quote := b[i]
+			for i++; i < len(b) && b[i] != quote && b[i] != '\n'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '/':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		case b[i] == '/' && i+1 < len(b) && b[i+1] == '*':
+			stop := len(b)
+			if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+				stop = i + end + 4
+			}
+
+			for ; i < stop; i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+
+			i--
+		}
+	}
+
+	return string(b)
+}
+
+// splitCList splits the body of a C initializer list on the commas that
+// aren't inside a string literal, returning each item with its offset.
+func splitCList(s string) ([]string, []int) {
+	var (
+		items   []string
+		offsets []int
+		start   int
+		quote   byte
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items, offsets = append(items, s[start:i]), append(offsets, start)
+			start = i + 1
+		}
+	}
+
+	return append(items, s[start:]), append(offsets, start)
+}
+
+// parseSt reads the colorname array of a suckless st config.h without
+// compiling it. Designated initializers such as "[255] = 0" move the index
+// of the following entries, and the defaultfg, defaultbg and defaultcs
+// indexes are resolved against the array.
+func parseSt(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	content := stripCComments(joinLines(lines))
+
+	loc := reStColorname.FindStringIndex(content)
+	if loc == nil {
+		return nil, errors.New("no colorname array found in st configuration")
+	}
+
+	end := strings.Index(content[loc[1]:], "}")
+	if end < 0 {
+		return nil, errors.New("colorname array in st configuration is never closed")
+	}
+
+	lineAt := func(offset int) sourceLine {
+		n := strings.Count(content[:offset], "\n")
+		if n >= len(lines) {
+			n = len(lines) - 1
+		}
+
+		return lines[n]
+	}
+
+	colors := map[int]resource{}
+	items, offsets := splitCList(content[loc[1] : loc[1]+end])
+
+	for i, index := 0, 0; i < len(items); i++ {
+		m := reStItem.FindStringSubmatch(items[i])
+		if m[1] == "" && m[2] == "" {
+			continue
+		}
+
+		if m[1] != "" {
+			index, _ = strconv.Atoi(m[1])
+		}
+
+		if strings.HasPrefix(m[2], `"`) {
+			source := lineAt(loc[1] + offsets[i] + len(items[i]) - len(strings.TrimLeft(items[i], " \t\n")))
+
+			value, err := strconv.Unquote(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid string in colorname array: %s", source, m[2])
+			}
+
+			colors[index] = resource{value: value, source: source}
+		}
+
+		index++
+	}
+
+	values := map[string]resource{}
+	for i := 0; i < 16; i++ {
+		if res, found := colors[i]; found {
+			debugf("%s: using colorname[%d]: %s", res.source, i, res.value)
+			values[fmt.Sprintf("color%d", i)] = res
+		}
+	}
+
+	indexes := map[string]int{}
+	for _, m := range reStDefault.FindAllStringSubmatch(content, -1) {
+		indexes[m[1]], _ = strconv.Atoi(m[2])
+	}
+
+	for name, key := range stDefaultKeys {
+		n, found := indexes[name]
+		if !found {
+			n = stDefaults[name]
+			debugf("%s has no %s, using st's old default of %d", sourceName(lines[0].file), name, n)
+		}
+
+		if res, found := colors[n]; found {
+			debugf("%s: using colorname[%d] for %s: %s", res.source, n, name, res.value)
+			values[key] = res
+		}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "st configuration")
+	return values, nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var reColourSetting = regexp.MustCompile(`^(?i:colour)[0-9]+$`)
+
+// sessionTemplate holds extra PuTTY and KiTTY session settings read from
+// a template file, written to every session after the colors.
+type sessionTemplate struct {
+	values []templateValue
+}
+
+// templateValue is a single setting of a session template: a string,
+// which can reference the session name as "{{.SessionName}}", or a dword.
+type templateValue struct {
+	name   string
+	text   *template.Template
+	dword  uint32
+	isText bool
+}
+
+// templateData is what the string values of a template can reference.
+type templateData struct {
+	SessionName string
+}
+
+// readSessionTemplate reads a session template, either a JSON object or
+// TOML style "Name = value" lines, where quoted values are strings and
+// integers or booleans are dwords. Color settings are rejected, since
+// they come from the theme.
+func readSessionTemplate(fname string) (*sessionTemplate, error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the session template: %s", err.Error())
+	}
+
+	var raw []rawTemplateValue
+	if strings.EqualFold(filepath.Ext(fname), ".json") {
+		raw, err = parseJSONTemplate(fname, content)
+	} else {
+		raw, err = parseTOMLTemplate(fname, content)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	t := &sessionTemplate{}
+	seen := map[string]bool{}
+
+	for _, r := range raw {
+		if reColourSetting.MatchString(r.name) {
+			return nil, fmt.Errorf("%s: %s is a color, colors come from the theme and can't be set in a template", r.source, r.name)
+		}
+
+		if seen[strings.ToLower(r.name)] {
+			return nil, fmt.Errorf("%s: %s is set more than once", r.source, r.name)
+		}
+
+		seen[strings.ToLower(r.name)] = true
+
+		v := templateValue{name: r.name, dword: r.dword, isText: r.isText}
+		if r.isText {
+			if v.text, err = template.New(r.name).Option("missingkey=error").Parse(r.text); err != nil {
+				return nil, fmt.Errorf("%s: invalid value for %s: %s", r.source, r.name, err.Error())
+			}
+
+			// catch references to unknown fields now rather than
+			// halfway through writing the sessions
+			if err := v.text.Execute(&bytes.Buffer{}, templateData{}); err != nil {
+				return nil, fmt.Errorf("%s: invalid value for %s: %s", r.source, r.name, err.Error())
+			}
+		}
+
+		t.values = append(t.values, v)
+	}
+
+	return t, nil
+}
+
+// settings returns the template values for the given session, with the
+// placeholders in strings filled in.
+func (t *sessionTemplate) settings(sname string) ([]sessionSetting, error) {
+	settings := make([]sessionSetting, 0, len(t.values))
+
+	for _, v := range t.values {
+		if !v.isText {
+			settings = append(settings, sessionSetting{name: v.name, value: v.dword})
+			continue
+		}
+
+		var b strings.Builder
+		if err := v.text.Execute(&b, templateData{SessionName: sname}); err != nil {
+			return nil, fmt.Errorf("can't fill in %s from the session template: %s", v.name, err.Error())
+		}
+
+		settings = append(settings, sessionSetting{name: v.name, text: b.String(), isText: true})
+	}
+
+	return settings, nil
+}
+
+// has reports whether the template sets the named setting.
+func (t *sessionTemplate) has(name string) bool {
+	for _, v := range t.values {
+		if strings.EqualFold(v.name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type rawTemplateValue struct {
+	source string
+	name   string
+	text   string
+	dword  uint32
+	isText bool
+}
+
+func parseTOMLTemplate(fname string, content []byte) ([]rawTemplateValue, error) {
+	var values []rawTemplateValue
+
+	for i, line := range strings.Split(string(content), "\n") {
+		src := sourceLine{file: fname, num: i + 1}
+		text := strings.TrimSpace(line)
+
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			return nil, fmt.Errorf("%s: session templates don't have sections", src)
+		}
+
+		m := reINIEntry.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("%s: expected a \"Name = value\" line", src)
+		}
+
+		value := templateValueText(m[2])
+		v := rawTemplateValue{source: src.String(), name: strings.Trim(m[1], `"`)}
+
+		var err error
+		switch {
+		case strings.HasPrefix(value, `"`):
+			v.isText = true
+			if v.text, err = strconv.Unquote(value); err != nil {
+				return nil, fmt.Errorf("%s: invalid string for %s: %s", src, v.name, value)
+			}
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, fmt.Errorf("%s: invalid string for %s: %s", src, v.name, value)
+			}
+
+			v.isText, v.text = true, value[1:len(value)-1]
+		case value == "true", value == "false":
+			if value == "true" {
+				v.dword = 1
+			}
+		default:
+			n, err := strconv.ParseUint(value, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s: the value of %s must be a quoted string, a positive integer or a boolean, got %s", src, v.name, value)
+			}
+
+			v.dword = uint32(n)
+		}
+
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// templateValueText drops a trailing "#" comment from a TOML value,
+// leaving any "#" inside a quoted string alone.
+func templateValueText(s string) string {
+	s = strings.TrimSpace(s)
+
+	if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+		for i := 1; i < len(s); i++ {
+			if s[0] == '"' && s[i] == '\\' {
+				i++
+				continue
+			}
+
+			if s[i] == s[0] {
+				return s[:i+1]
+			}
+		}
+
+		return s
+	}
+
+	if pos := strings.IndexByte(s, '#'); pos >= 0 {
+		s = strings.TrimSpace(s[:pos])
+	}
+
+	return s
+}
+
+func parseJSONTemplate(fname string, content []byte) ([]rawTemplateValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("%s: a JSON session template must be an object of setting names to values", fname)
+	}
+
+	var values []rawTemplateValue
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON session template: %s", fname, err.Error())
+		}
+
+		name := tok.(string)
+
+		var raw interface{}
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON session template: %s", fname, err.Error())
+		}
+
+		v := rawTemplateValue{source: fname, name: name}
+
+		switch raw := raw.(type) {
+		case string:
+			v.isText, v.text = true, raw
+		case bool:
+			if raw {
+				v.dword = 1
+			}
+		case json.Number:
+			n, err := strconv.ParseUint(raw.String(), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s: the value of %s must be a positive integer, got %s", fname, name, raw)
+			}
+
+			v.dword = uint32(n)
+		default:
+			return nil, fmt.Errorf("%s: the value of %s must be a string, a positive integer or a boolean", fname, name)
+		}
+
+		values = append(values, v)
+	}
+
+	return values, nil
+}
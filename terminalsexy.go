@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// terminalSexyScheme is the layout of the JSON export of terminal.sexy.
+type terminalSexyScheme struct {
+	Name       string   `json:"name"`
+	Author     string   `json:"author"`
+	Color      []string `json:"color"`
+	Foreground string   `json:"foreground"`
+	Background string   `json:"background"`
+}
+
+func detectTerminalSexy(content string) bool {
+	content = strings.TrimSpace(content)
+	return strings.HasPrefix(content, "{") && strings.Contains(content, `"color"`) && strings.Contains(content, `"foreground"`)
+}
+
+// parseTerminalSexy reads a terminal.sexy JSON export. The export has no
+// cursor color, so the foreground is used instead.
+func parseTerminalSexy(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	var scheme terminalSexyScheme
+	if err := json.Unmarshal([]byte(joinLines(lines)), &scheme); err != nil {
+		return nil, fmt.Errorf("invalid terminal.sexy JSON: %s", err.Error())
+	}
+
+	if len(scheme.Color) != 16 {
+		return nil, fmt.Errorf("terminal.sexy \"color\" array must have 16 entries, found %d", len(scheme.Color))
+	}
+
+	values := map[string]resource{}
+
+	set := func(key, field, value string) {
+		if value == "" {
+			return
+		}
+
+		debugf("%s: using %s: %s", lines[0].file, field, value)
+		values[key] = resource{value: value, source: fieldSource(lines, field)}
+	}
+
+	set("foreground", "foreground", scheme.Foreground)
+	set("background", "background", scheme.Background)
+
+	for i, value := range scheme.Color {
+		set(fmt.Sprintf("color%d", i), fmt.Sprintf("color[%d]", i), value)
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "terminal.sexy")
+	return values, nil
+}
+
+// writeTerminalSexy writes the colors as a terminal.sexy JSON export,
+// which can be imported into its editor.
+func writeTerminalSexy(w io.Writer, sname string, p palette, opts encodeOptions) error {
+	scheme := terminalSexyScheme{
+		Name:       sname,
+		Author:     opts.author,
+		Foreground: hexColor(p.keys["foreground"]),
+		Background: hexColor(p.keys["background"]),
+	}
+
+	for _, key := range paletteKeys() {
+		scheme.Color = append(scheme.Color, hexColor(p.keys[key]))
+	}
+
+	out, err := json.MarshalIndent(scheme, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode the scheme: %s", err.Error())
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTerminalSexyToReg(t *testing.T) {
+	values := readTestValues(t, "ocean.terminalsexy.json")
+
+	if got := values["foreground"].format; got != "terminalsexy" {
+		t.Fatalf("read as %q, want terminalsexy", got)
+	}
+
+	// the export has no cursor color, the foreground stands in for it
+	if got := values["cursorColor"].value; got != "#c0c5ce" {
+		t.Errorf("cursorColor = %q, want the foreground #c0c5ce", got)
+	}
+
+	p, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := findOutputFormat("kitty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.encode(&b, "Ocean", p); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "ocean.terminalsexy.reg", b.Bytes())
+}
+
+func TestWriteTerminalSexy(t *testing.T) {
+	p := builtinTestPalette(t, "solarized-dark")
+
+	var b bytes.Buffer
+	if err := writeTerminalSexy(&b, "Solarized Dark", p, encodeOptions{author: "Ethan Schoonover"}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "solarized-dark.terminalsexy.json", b.Bytes())
+}
+
+func TestTerminalSexyRoundTrip(t *testing.T) {
+	// converting the export back gives the same file terminal.sexy wrote
+	path := filepath.Join("testdata", "ocean.terminalsexy.json")
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := readTestPalette(t, "ocean.terminalsexy.json")
+
+	var b bytes.Buffer
+	if err := writeTerminalSexy(&b, "Ocean", p, encodeOptions{author: "Chris Kempson (http://chriskempson.com)"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", path, b.Bytes(), want)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// terminatorSettings maps the color settings of a Terminator profile to
+// the Xresources keys they set.
+var terminatorSettings = map[string]string{
+	"foreground_color": "foreground",
+	"background_color": "background",
+	"cursor_color":     "cursorColor",
+	"cursor_bg_color":  "cursorColor",
+	"cursor_fg_color":  "cursorColor2",
+}
+
+func detectTerminator(content string) bool {
+	return strings.Contains(content, "[profiles]") &&
+		(strings.Contains(content, "palette") || strings.Contains(content, "foreground_color"))
+}
+
+// terminatorProfiles returns the settings of each profile nested as
+// "[[name]]" under the "[profiles]" section of a Terminator config.
+func terminatorProfiles(lines []sourceLine) map[string]map[string]kvEntry {
+	profiles := map[string]map[string]kvEntry{}
+	var top, profile string
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line.text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if m := reINISection.FindStringSubmatch(line.text); m != nil {
+			switch depth := len(m[1]); {
+			case depth == 1:
+				top, profile = m[2], ""
+			case depth == 2 && top == "profiles":
+				profile = m[2]
+				if profiles[profile] == nil {
+					profiles[profile] = map[string]kvEntry{}
+				}
+			default:
+				profile = ""
+			}
+
+			continue
+		}
+
+		m := reINIEntry.FindStringSubmatch(line.text)
+		if m == nil || profile == "" {
+			continue
+		}
+
+		profiles[profile][m[1]] = kvEntry{section: profile, key: m[1], value: unquoteValue(m[2]), depth: 2, source: line}
+	}
+
+	return profiles
+}
+
+// selectTerminatorProfile picks the profile named with --profile, or the
+// "default" one, with the settings it doesn't set taken from "default" as
+// Terminator does.
+func selectTerminatorProfile(lines []sourceLine, opts decodeOptions) (string, map[string]kvEntry, error) {
+	profiles := terminatorProfiles(lines)
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	name := opts.profile
+	if name == "" {
+		name = "default"
+		if len(profiles) == 1 {
+			name = names[0]
+		}
+	}
+
+	selected, found := profiles[name]
+	switch {
+	case len(profiles) == 0:
+		return "", nil, errors.New("no profiles found in Terminator config")
+	case !found:
+		return "", nil, fmt.Errorf("no profile named %q in Terminator config, available profiles: %s", name, strings.Join(names, ", "))
+	}
+
+	settings := map[string]kvEntry{}
+	for key, e := range profiles["default"] {
+		settings[key] = e
+	}
+
+	for key, e := range selected {
+		settings[key] = e
+	}
+
+	return name, settings, nil
+}
+
+// parseTerminator reads a profile from a Terminator config file, whose
+// palette is a single string of 16 colors separated by ":".
+func parseTerminator(lines []sourceLine, opts decodeOptions) (map[string]resource, error) {
+	name, settings, err := selectTerminatorProfile(lines, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]resource{}
+
+	for _, key := range sortedKeys(terminatorSettings) {
+		if e, found := settings[key]; found {
+			if e.section != name {
+				debugf("%s: profile %q has no %s, using the one from the default profile", e.source, name, key)
+			}
+
+			debugf("%s: using %s: %s", e.source, key, e.value)
+			values[terminatorSettings[key]] = e.resource()
+		}
+	}
+
+	if palette, found := settings["palette"]; found {
+		colors := strings.Split(palette.value, ":")
+		if len(colors) != 16 {
+			return nil, fmt.Errorf("%s: palette has %d colors, expected 16", palette.source, len(colors))
+		}
+
+		if palette.section != name {
+			debugf("%s: profile %q has no palette, using the one from the default profile", palette.source, name)
+		}
+
+		debugf("%s: using palette: %s", palette.source, palette.value)
+		for i, value := range colors {
+			values[fmt.Sprintf("color%d", i)] = resource{value: strings.TrimSpace(value), source: palette.source}
+		}
+	}
+
+	if e, found := settings["use_theme_colors"]; found && strings.EqualFold(e.value, "true") {
+		notef("profile %q uses the colors of the system theme, its own foreground and background may not be what you see", name)
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "Terminator profile")
+	return values, nil
+}
+
+// terminatorName returns the name of the selected profile.
+func terminatorName(lines []sourceLine, opts decodeOptions) string {
+	name, _, err := selectTerminatorProfile(lines, opts)
+	if err != nil {
+		return ""
+	}
+
+	return name
+}
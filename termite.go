@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reTermiteDetect = regexp.MustCompile(`(?m)^[ \t]*color0[ \t]*=`)
+
+var termiteSettings = map[string]string{
+	"foreground":        "foreground",
+	"foreground_bold":   "colorBD",
+	"background":        "background",
+	"cursor":            "cursorColor",
+	"cursor_foreground": "cursorColor2",
+	"highlight":         "highlightColor",
+}
+
+func detectTermite(content string) bool {
+	return strings.Contains(content, "[colors]") && reTermiteDetect.MatchString(content)
+}
+
+// parseTermite reads the [colors] section of a termite config file.
+// Values written as "rgba(r, g, b, a)" are accepted, but since KiTTY has
+// no transparency the alpha channel is dropped.
+func parseTermite(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+
+	for _, e := range parseINI(lines) {
+		if e.section != "colors" {
+			continue
+		}
+
+		key, found := termiteSettings[e.key]
+		if !found {
+			n, err := strconv.Atoi(strings.TrimPrefix(e.key, "color"))
+			if !strings.HasPrefix(e.key, "color") || err != nil || n > 15 {
+				continue
+			}
+
+			key = fmt.Sprintf("color%d", n)
+		}
+
+		res := e.resource()
+		if value, dropped := dropAlpha(res.value); dropped {
+			warnf("%s: dropping the alpha channel of %s, KiTTY doesn't support transparency", e.source, e.key)
+			res.value = value
+		}
+
+		debugf("%s: using %s: %s", e.source, e.key, res.value)
+		values[key] = res
+	}
+
+	return values, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var reTermuxDetect = regexp.MustCompile(`(?m)^[ \t]*(?:color0|foreground)[ \t]*=[ \t]*#`)
+
+var termuxSettings = map[string]string{
+	"foreground": "foreground",
+	"background": "background",
+	"cursor":     "cursorColor",
+}
+
+func detectTermux(content string) bool {
+	return !strings.Contains(content, "[") && reTermuxDetect.MatchString(content)
+}
+
+// parseTermux reads a Termux colors.properties file. Lines starting with
+// "#" are comments, but a "#" after the "=" starts a hex color. Many
+// themes have no cursor line, in which case the foreground is used.
+func parseTermux(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+
+	for _, e := range parseINI(lines) {
+		key, found := termuxSettings[e.key]
+		if !found {
+			n, err := strconv.Atoi(strings.TrimPrefix(e.key, "color"))
+			if !strings.HasPrefix(e.key, "color") || err != nil || n > 15 {
+				continue
+			}
+
+			key = fmt.Sprintf("color%d", n)
+		}
+
+		debugf("%s: using %s: %s", e.source, e.key, e.value)
+		values[key] = e.resource()
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "Termux color scheme")
+	return values, nil
+}
+
+// writeTermux writes the colors as a Termux colors.properties file, to be
+// placed in ~/.termux.
+func writeTermux(w io.Writer, _ string, p palette, _ encodeOptions) error {
+	for _, name := range themeColors() {
+		fmt.Fprintf(w, "%s=%s\n", name[0], hexColor(p.keys[name[1]]))
+	}
+
+	return nil
+}
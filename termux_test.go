@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteTermux(t *testing.T) {
+	p := builtinTestPalette(t, "dracula")
+
+	var b bytes.Buffer
+	if err := writeTermux(&b, "Dracula", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "dracula.properties", b.Bytes())
+
+	// Termux reads the file as Java properties, which a BOM or a carriage
+	// return would end up in the keys and values of
+	if bytes.HasPrefix(b.Bytes(), []byte{0xef, 0xbb, 0xbf}) {
+		t.Error("the file starts with a BOM")
+	}
+
+	if bytes.Contains(b.Bytes(), []byte("\r")) {
+		t.Error("the file has CRLF line endings")
+	}
+
+	// and it reads back as the same colors
+	lines, err := scanLines("colors.properties", bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := parseTermux(lines, decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range append(paletteKeys(), "foreground", "background", "cursorColor") {
+		if got.keys[key] != p.keys[key] {
+			t.Errorf("%s read back as %v, want %v", key, got.keys[key], p.keys[key])
+		}
+	}
+}
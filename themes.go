@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// builtinPrefix marks input names referring to one of the embedded
+// themes, as selected with --theme.
+const builtinPrefix = "builtin:"
+
+//go:embed themes/*.Xresources
+var builtinThemes embed.FS
+
+// builtinThemeNames returns the names of the embedded themes, sorted.
+func builtinThemeNames() []string {
+	entries, _ := builtinThemes.ReadDir("themes")
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), path.Ext(e.Name())))
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// readBuiltinTheme returns the lines of an embedded theme. Unknown names
+// are reported along with the closest existing one.
+func readBuiltinTheme(fname string) ([]sourceLine, error) {
+	name := strings.TrimPrefix(fname, builtinPrefix)
+
+	b, err := builtinThemes.ReadFile("themes/" + name + ".Xresources")
+	if err != nil {
+		names := builtinThemeNames()
+		return nil, fmt.Errorf("unknown theme %q, did you mean %q? available themes: %s", name, closestName(name, names), strings.Join(names, ", "))
+	}
+
+	return scanLines(fname, bytes.NewReader(b))
+}
+
+// listBuiltinThemes prints the embedded themes with their foreground and
+// background colors.
+func listBuiltinThemes(w io.Writer) error {
+	for _, name := range builtinThemeNames() {
+		lines, err := readBuiltinTheme(builtinPrefix + name)
+		if err != nil {
+			return err
+		}
+
+		values, err := parseXresources(lines, decodeOptions{})
+		if err != nil {
+			return err
+		}
+
+		fg, err := parseColor(values["foreground"].value)
+		if err != nil {
+			return values["foreground"].invalid("foreground", err)
+		}
+
+		bg, err := parseColor(values["background"].value)
+		if err != nil {
+			return values["background"].invalid("background", err)
+		}
+
+		fmt.Fprintf(w, "%-16s fg %s  bg %s\n", name, hexColor(fg), hexColor(bg))
+	}
+
+	return nil
+}
+
+// closestName returns the name with the smallest edit distance to s.
+func closestName(s string, names []string) string {
+	best, bestDistance := "", -1
+	for _, name := range names {
+		if d := editDistance(s, name); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// flagGroup is a titled section of the --help output.
+type flagGroup struct {
+	title string
+	names []string
+}
+
+// flagGroups sorts the flags into the sections of the --help output.
+// Flags missing from here are listed at the end, so a new flag is never
+// left out of it.
+var flagGroups = []flagGroup{
+	{"Input", []string{"input", "from", "theme", "list-themes", "scheme", "profile", "no-include", "member", "all", "crawl", "strict", "name-template"}},
+	{"Downloads", []string{"insecure", "no-cache", "refresh", "timeout", "retries"}},
+	{"Output", []string{"to", "output", "out-dir", "combined", "force", "backup", "encoding", "shell", "author", "css-prefix", "css-class", "png-scale"}},
+	{"PuTTY and KiTTY sessions", []string{"session", "sessions-file", "default-settings", "bold-as-colour", "force-palette", "template", "registry-root", "registry-path", "clean", "i-know-this-deletes-everything", "merge", "in-place", "write-registry", "diff-registry", "create", "yes"}},
+	{"Preview", []string{"preview", "list", "apply", "preview-seconds", "watch"}},
+	{"Other", []string{"version", "json", "verbose", "vv", "quiet", "config", "no-config"}},
+}
+
+// flagAliases are the one letter spellings of the most used flags.
+var flagAliases = map[string]string{
+	"input":   "i",
+	"session": "s",
+	"output":  "o",
+	"verbose": "v",
+	"quiet":   "q",
+}
+
+// addFlagAliases defines the one letter spellings of flagAliases, sharing
+// the value of the flag they stand for, for the flags the set has and
+// unless the set already defines them.
+func addFlagAliases(fs *flag.FlagSet) {
+	for name, alias := range flagAliases {
+		if f := fs.Lookup(name); f != nil && fs.Lookup(alias) == nil {
+			fs.Var(f.Value, alias, "alias of --"+name)
+		}
+	}
+}
+
+// printCommands writes the --help output of the tool itself: the commands,
+// followed by the flags they all share.
+func printCommands(w io.Writer, root *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  urxvt-kitty <command> [flags] [arguments]")
+	fmt.Fprintln(w, "  urxvt-kitty [flags] <file>... [<session>]    same as convert")
+	fmt.Fprintln(w, "\nCommands:")
+
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+
+	fmt.Fprintln(w, "\nFlags shared by all the commands:")
+	for _, name := range sharedFlags {
+		printFlag(w, root.Lookup(name))
+	}
+
+	fmt.Fprintln(w, "\nExit status:")
+	fmt.Fprintln(w, "  0  success")
+	fmt.Fprintln(w, "  1  invalid flags or arguments")
+	fmt.Fprintln(w, "  2  an input file that doesn't exist or can't be read")
+	fmt.Fprintln(w, "  3  an input without colors, or with invalid values")
+	fmt.Fprintln(w, "  4  an input missing some of the required colors")
+	fmt.Fprintln(w, "  5  an output that can't be written")
+	fmt.Fprintln(w, "With --diff-registry, 1 means the session differs, and every error other than")
+	fmt.Fprintln(w, "an unknown flag exits with 2 instead.")
+
+	fmt.Fprintln(w, "\nRun \"urxvt-kitty <command> --help\" for the flags of each command.")
+}
+
+// printCommandUsage writes the --help output of a command other than
+// convert, which has so many flags that they're split into sections.
+func printCommandUsage(w io.Writer, name string, fs *flag.FlagSet) {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			fmt.Fprintf(w, "Usage:\n  urxvt-kitty %s %s\n\n", cmd.name, cmd.args)
+			fmt.Fprintf(w, "%s%s.\n", strings.ToUpper(cmd.summary[:1]), cmd.summary[1:])
+		}
+	}
+
+	aliases := map[string]bool{}
+	for _, alias := range flagAliases {
+		aliases[alias] = true
+	}
+
+	fmt.Fprintln(w, "\nFlags:")
+	fs.VisitAll(func(f *flag.Flag) {
+		if !aliases[f.Name] {
+			printFlag(w, f)
+		}
+	})
+}
+
+// printUsage writes the --help output of convert: how to call it, followed
+// by the flags in their sections.
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  urxvt-kitty convert [flags] -i <file> [-s <session>]")
+	fmt.Fprintln(w, "  urxvt-kitty convert [flags] <file>... [<session>]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Converts a terminal color theme, by default into a KiTTY session .reg file.")
+	fmt.Fprintln(w, "Use \"-\" as the file to read from stdin, or an http(s) URL or")
+	fmt.Fprintln(w, "github:owner/repo/path@ref to download it. Later files override earlier")
+	fmt.Fprintln(w, "ones. The session name defaults to the theme name for formats that have one.")
+
+	listed := map[string]bool{}
+	for _, alias := range flagAliases {
+		listed[alias] = true
+	}
+
+	for _, group := range flagGroups {
+		fmt.Fprintf(w, "\n%s:\n", group.title)
+
+		for _, name := range group.names {
+			printFlag(w, fs.Lookup(name))
+			listed[name] = true
+		}
+	}
+
+	var other []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		if !listed[f.Name] {
+			other = append(other, f)
+		}
+	})
+
+	if len(other) > 0 {
+		fmt.Fprintln(w, "\nMore flags:")
+		for _, f := range other {
+			printFlag(w, f)
+		}
+	}
+
+	fmt.Fprintln(w, "\nGet themes from: http://dotshare.it/category/terms/colors/")
+}
+
+func printFlag(w io.Writer, f *flag.Flag) {
+	names := "--" + f.Name
+	if alias := flagAliases[f.Name]; alias != "" {
+		names = "-" + alias + ", " + names
+	}
+
+	kind, usage := flag.UnquoteUsage(f)
+	if kind != "" {
+		names += " " + kind
+	}
+
+	switch {
+	case f.DefValue == "" || f.DefValue == "false" || f.DefValue == "0":
+	case kind == "string":
+		usage += fmt.Sprintf(" (default %q)", f.DefValue)
+	default:
+		usage += fmt.Sprintf(" (default %s)", f.DefValue)
+	}
+
+	fmt.Fprintf(w, "  %s\n      %s\n", names, usage)
+}
+
+// flagError rewrites the errors of the flag package to point at --help,
+// naming the closest existing flag for flags that don't exist.
+func flagError(fs *flag.FlagSet, err error) error {
+	const prefix = "flag provided but not defined: "
+
+	msg := err.Error()
+	if !strings.HasPrefix(msg, prefix) {
+		return fmt.Errorf("%s, run \"%s --help\" for the list of flags", msg, fs.Name())
+	}
+
+	name := strings.TrimLeft(strings.TrimPrefix(msg, prefix), "-")
+
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) > 1 {
+			names = append(names, f.Name)
+		}
+	})
+
+	if best := closestName(name, names); editDistance(name, best) <= 2 {
+		return fmt.Errorf("unknown flag --%s, did you mean --%s?", name, best)
+	}
+
+	return fmt.Errorf("unknown flag --%s, run \"%s --help\" for the list of flags", name, fs.Name())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// The build information printed by --version. Release builds set them
+// with -ldflags "-X main.version=v1.2.3 -X main.commit=... -X main.date=...",
+// otherwise they are read from the information the Go toolchain embeds.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo is what --version prints.
+type buildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// readBuildInfo returns the build information, preferring the values set
+// with -ldflags over the ones embedded by the Go toolchain, and "unknown"
+// for anything neither has.
+func readBuildInfo() buildInfo {
+	info := buildInfo{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		modified := false
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.Date == "":
+				info.Date = s.Value
+			case s.Key == "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	for _, field := range []*string{&info.Version, &info.Commit, &info.Date} {
+		if *field == "" {
+			*field = "unknown"
+		}
+	}
+
+	return info
+}
+
+// writeVersion prints the build information as a single line, or as JSON
+// when asJSON is set.
+func writeVersion(w io.Writer, asJSON bool) error {
+	info := readBuildInfo()
+
+	if asJSON {
+		b, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%s\n", b)
+		return nil
+	}
+
+	fmt.Fprintf(w, "urxvt-kitty %s commit %s built %s %s\n", info.Version, info.Commit, info.Date, info.GoVersion)
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// vimWriter returns a writer for the terminal_color_N globals read by the
+// Vim and Neovim embedded terminals, using the given comment marker and
+// assignment format.
+func vimWriter(comment, assign string) func(io.Writer, string, palette, encodeOptions) error {
+	return func(w io.Writer, sname string, p palette, _ encodeOptions) error {
+		sources := make([]string, 0, len(p.sources))
+		for _, src := range p.sources {
+			sources = append(sources, fmt.Sprintf("%s (%s)", src.File, src.Format))
+		}
+
+		fmt.Fprintf(w, "%s %s, converted from %s\n", comment, strings.ReplaceAll(sname, "\n", " "), strings.Join(sources, ", "))
+
+		for _, name := range [][2]string{{"foreground", "foreground"}, {"background", "background"}, {"cursor", "cursorColor"}} {
+			fmt.Fprintf(w, "%s %s %s\n", comment, name[0], hexColor(p.keys[name[1]]))
+		}
+
+		for i, key := range paletteKeys() {
+			fmt.Fprintf(w, assign+"\n", i, hexColor(p.keys[key]))
+		}
+
+		return nil
+	}
+}
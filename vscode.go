@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// vscodeFields maps the VS Code terminal color customizations to the
+// Xresources keys they set. The palette keys are added in init.
+var vscodeFields = map[string]string{
+	"terminal.foreground":          "foreground",
+	"terminal.background":          "background",
+	"terminalCursor.foreground":    "cursorColor",
+	"terminalCursor.background":    "cursorColor2",
+	"terminal.selectionBackground": "highlightColor",
+	"terminal.selectionForeground": "highlightTextColor",
+}
+
+func init() {
+	for i, name := range ansiColorNames {
+		name = strings.ToUpper(name[:1]) + name[1:]
+		vscodeFields["terminal.ansi"+name] = fmt.Sprintf("color%d", i)
+		vscodeFields["terminal.ansiBright"+name] = fmt.Sprintf("color%d", i+8)
+	}
+}
+
+func detectVSCode(content string) bool {
+	return strings.Contains(content, `"terminal.ansi`)
+}
+
+// parseVSCode reads the terminal colors of VS Code, either from a bare
+// "workbench.colorCustomizations" object or from a full settings.json
+// holding one. Colors with an alpha channel are accepted, but the alpha is
+// dropped.
+func parseVSCode(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(stripJSONComments([]byte(joinLines(lines))), &settings); err != nil {
+		return nil, fmt.Errorf("invalid VS Code JSON: %s", err.Error())
+	}
+
+	customizations := settings
+	if raw, found := settings["workbench.colorCustomizations"]; found {
+		if err := json.Unmarshal(raw, &customizations); err != nil {
+			return nil, fmt.Errorf("invalid workbench.colorCustomizations object: %s", err.Error())
+		}
+	}
+
+	values := map[string]resource{}
+	for _, field := range sortedKeys(vscodeFields) {
+		raw, found := customizations[field]
+		if !found {
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("invalid value for %s, expected a string: %s", field, raw)
+		}
+
+		if v, dropped := dropAlpha(value); dropped {
+			warnf("%s: dropping the alpha channel of %s, KiTTY doesn't support transparency", sourceName(lines[0].file), field)
+			value = v
+		}
+
+		debugf("%s: using %s: %s", lines[0].file, field, value)
+		values[vscodeFields[field]] = resource{value: value, source: fieldSource(lines, field)}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "VS Code color customization")
+	return values, nil
+}
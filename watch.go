@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// watchInterval is how often --watch checks the input files.
+	watchInterval = 200 * time.Millisecond
+
+	// watchSettle is how long the files must stay unchanged before
+	// converting them again, since editors often write a file more than
+	// once when saving it.
+	watchSettle = 300 * time.Millisecond
+)
+
+// watcher waits for any of a set of files to change.
+type watcher interface {
+	// wait blocks until one of the files changes, returning its name, or
+	// until the context is done, returning its error.
+	wait(ctx context.Context, files []string) (string, error)
+}
+
+// watchInput runs the conversion, then again each time one of the input
+// files, or a file they include, changes, until the context is done.
+// Errors are reported without stopping, so a theme can be fixed while it's
+// being watched. round counts the runs, starting from 0.
+func watchInput(ctx context.Context, w watcher, fnames []string, includes bool, run func(round int) error) error {
+	files := watchedFiles(fnames, includes)
+	if len(files) == 0 {
+		return errors.New("--watch needs an input file to watch, not stdin, a URL or a built-in theme")
+	}
+
+	for round := 0; ; round++ {
+		if err := run(round); err != nil {
+			printError(err)
+		}
+
+		// includes can be added or dropped between runs
+		files = watchedFiles(fnames, includes)
+		if round == 0 {
+			notef("watching %s for changes, press Ctrl-C to stop", strings.Join(files, ", "))
+		}
+
+		changed, err := w.wait(ctx, files)
+		if err != nil {
+			return nil
+		}
+
+		debugf("%s changed, converting again", changed)
+	}
+}
+
+// watchedFiles returns the local input files, followed by the files they
+// include when includes are followed. Files that can't be read are still
+// watched, for when they're created.
+func watchedFiles(fnames []string, includes bool) []string {
+	var files []string
+	seen := map[string]bool{}
+
+	var add func(fname string, depth int)
+	add = func(fname string, depth int) {
+		if fname == "-" || isURL(fname) || strings.HasPrefix(fname, builtinPrefix) || seen[fname] {
+			return
+		}
+
+		if _, ok := expandGitHub(fname); ok {
+			return
+		}
+
+		seen[fname] = true
+		files = append(files, fname)
+
+		if !includes || depth == maxIncludeDepth {
+			return
+		}
+
+		lines, err := readLines(fname)
+		if err != nil {
+			return
+		}
+
+		for _, line := range lines {
+			if m := reIncludes.FindStringSubmatch(line.text); m != nil {
+				if included, err := resolveInclude(fname, m[1]); err == nil {
+					add(included, depth+1)
+				}
+			}
+		}
+	}
+
+	for _, fname := range fnames {
+		add(fname, 0)
+	}
+
+	return files
+}
+
+// pollWatcher is a watcher checking the files every interval, as the
+// standard library has no way to be notified of changes. A change is only
+// reported once the files have stayed the same for the settle time.
+type pollWatcher struct {
+	interval time.Duration
+	settle   time.Duration
+}
+
+func (pw pollWatcher) wait(ctx context.Context, files []string) (string, error) {
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+
+	last := statFiles(files)
+
+	var (
+		changed     string
+		lastChanged time.Time
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+
+		current := statFiles(files)
+		for i, fname := range files {
+			if !last[i].same(current[i]) {
+				if changed == "" {
+					changed = fname
+				}
+
+				lastChanged = time.Now()
+			}
+		}
+
+		last = current
+
+		if changed != "" && time.Since(lastChanged) >= pw.settle {
+			return changed, nil
+		}
+	}
+}
+
+// fileState is what pollWatcher compares to tell a file changed.
+type fileState struct {
+	info os.FileInfo
+}
+
+func statFiles(files []string) []fileState {
+	states := make([]fileState, len(files))
+	for i, fname := range files {
+		// a missing file is a state too, as when it's being replaced
+		states[i].info, _ = os.Stat(fname)
+	}
+
+	return states
+}
+
+// same reports whether the file is unchanged. Replacing the file with
+// another one, as editors saving through a rename do, counts as a change
+// even when the size and time match.
+func (s fileState) same(other fileState) bool {
+	switch {
+	case s.info == nil || other.info == nil:
+		return s.info == nil && other.info == nil
+	case !os.SameFile(s.info, other.info):
+		return false
+	}
+
+	return s.info.Size() == other.info.Size() && s.info.ModTime().Equal(other.info.ModTime())
+}
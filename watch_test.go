@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeWatcher reports the changes it's given, one per wait, recording the
+// files it was asked to watch each time. Once out of changes it cancels
+// the watch, as Ctrl-C would.
+type fakeWatcher struct {
+	changes []string
+	cancel  context.CancelFunc
+	watched [][]string
+}
+
+func (fw *fakeWatcher) wait(ctx context.Context, files []string) (string, error) {
+	fw.watched = append(fw.watched, files)
+
+	if len(fw.changes) == 0 {
+		fw.cancel()
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	changed := fw.changes[0]
+	fw.changes = fw.changes[1:]
+	return changed, nil
+}
+
+// writeTestFile writes a file in dir, returning its path.
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestWatchInput(t *testing.T) {
+	dir := t.TempDir()
+	theme := writeTestFile(t, dir, "main.Xresources", "*.foreground: #c5c8c6\n")
+	colors := filepath.Join(dir, "colors.Xresources")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fw := &fakeWatcher{changes: []string{theme, theme, colors}, cancel: cancel}
+
+	var rounds []int
+	err := watchInput(ctx, fw, []string{theme}, true, func(round int) error {
+		rounds = append(rounds, round)
+
+		switch round {
+		case 0:
+			// the include is picked up once the file has it
+			writeTestFile(t, dir, "main.Xresources", "#include \"colors.Xresources\"\n")
+		case 1:
+			// a broken theme is reported without stopping the watch
+			return errors.New("no color codes found")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("watchInput() = %s, want it to stop cleanly once cancelled", err)
+	}
+
+	if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(rounds, want) {
+		t.Errorf("ran rounds %v, want %v", rounds, want)
+	}
+
+	if want := []string{theme, colors}; !reflect.DeepEqual(fw.watched[0], want) {
+		t.Errorf("watched %q, want %q, including the file that doesn't exist yet", fw.watched[0], want)
+	}
+}
+
+func TestWatchInputNothingToWatch(t *testing.T) {
+	err := watchInput(context.Background(), &fakeWatcher{}, []string{"-", "builtin:nord", "https://example.com/nord"}, true, func(int) error {
+		t.Error("ran the conversion with nothing to watch")
+		return nil
+	})
+
+	if err == nil {
+		t.Error("watchInput() succeeded, want an error for stdin, a built-in theme and a URL")
+	}
+}
+
+func TestFileStateSame(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "theme.Xresources", "*.foreground: #c5c8c6\n")
+
+	before := statFiles([]string{path})[0]
+	if !before.same(statFiles([]string{path})[0]) {
+		t.Error("an unchanged file counts as changed")
+	}
+
+	// vim saves by writing a new file and renaming it over the old one,
+	// which can leave the size and time as they were
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmp := writeTestFile(t, dir, "theme.Xresources~", "*.foreground: #ffffff\n")
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	replaced := statFiles([]string{path})[0]
+	if before.same(replaced) {
+		t.Error("a file replaced through a rename counts as unchanged")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	removed := statFiles([]string{path})[0]
+	if replaced.same(removed) {
+		t.Error("a removed file counts as unchanged")
+	}
+
+	if !removed.same(statFiles([]string{path})[0]) {
+		t.Error("a file missing twice counts as changed")
+	}
+}
+
+func TestPollWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "theme.Xresources", "*.foreground: #c5c8c6\n")
+	other := writeTestFile(t, dir, "colors.Xresources", "*.color0: #1d1f21\n")
+
+	pw := pollWatcher{interval: 5 * time.Millisecond, settle: 50 * time.Millisecond}
+
+	// two writes in a row, as some editors save, are a single change
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		os.WriteFile(other, []byte("*.color0: #000000\n"), 0644)
+		time.Sleep(10 * time.Millisecond)
+		os.WriteFile(other, []byte("*.color0: #000000\n*.color8: #969896\n"), 0644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	changed, err := pw.wait(ctx, []string{path, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed != other {
+		t.Errorf("wait() = %q, want %q", changed, other)
+	}
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond+pw.settle {
+		t.Errorf("wait() returned after %s, before the writes settled", elapsed)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := pw.wait(ctx, []string{path, other}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait() = %v with nothing changing, want the context error", err)
+	}
+}
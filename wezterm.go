@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func detectWezterm(content string) bool {
+	return strings.Contains(content, "[colors]") && strings.Contains(content, "ansi") && strings.Contains(content, "brights")
+}
+
+// parseWezterm reads a WezTerm color scheme TOML file, where the palette
+// is written as the "ansi" and "brights" arrays of the [colors] section.
+func parseWezterm(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	settings := map[string]string{
+		"foreground":   "foreground",
+		"background":   "background",
+		"cursor_bg":    "cursorColor",
+		"cursor_fg":    "cursorColor2",
+		"selection_bg": "highlightColor",
+		"selection_fg": "highlightTextColor",
+	}
+
+	values := map[string]resource{}
+
+	for _, e := range parseINI(lines) {
+		if e.section != "colors" {
+			continue
+		}
+
+		if key, found := settings[e.key]; found {
+			debugf("%s: using %s: %s", e.source, e.key, e.value)
+			values[key] = e.resource()
+			continue
+		}
+
+		offset := 0
+		switch e.key {
+		case "ansi":
+		case "brights":
+			offset = 8
+		default:
+			continue
+		}
+
+		items := parseTOMLArray(e.value)
+		if len(items) < 8 {
+			return nil, fmt.Errorf("%s: the %q array must have 8 colors, found %d", e.source, e.key, len(items))
+		}
+
+		for i, value := range items[:8] {
+			debugf("%s: using %s[%d]: %s", e.source, e.key, i, value)
+			values[fmt.Sprintf("color%d", i+offset)] = resource{value: value, source: e.source}
+		}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "WezTerm color scheme")
+	return values, nil
+}
+
+// weztermName returns the name of the scheme from its [metadata] section.
+func weztermName(lines []sourceLine, _ decodeOptions) string {
+	return kvLookup(parseINI(lines))["metadata.name"].value
+}
+
+// writeWezterm writes the colors as a standalone WezTerm color scheme,
+// to be placed in one of its color_scheme_dirs. The cursor text falls
+// back to the background so the cursor stays visible.
+func writeWezterm(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	hex := func(key string) string {
+		return fmt.Sprintf("%q", hexColor(p.keys[key]))
+	}
+
+	cursorText := "background"
+	if _, found := p.keys["cursorColor2"]; found {
+		cursorText = "cursorColor2"
+	}
+
+	fmt.Fprintln(w, "[colors]")
+	fmt.Fprintf(w, "foreground = %s\n", hex("foreground"))
+	fmt.Fprintf(w, "background = %s\n", hex("background"))
+	fmt.Fprintf(w, "cursor_bg = %s\n", hex("cursorColor"))
+	fmt.Fprintf(w, "cursor_fg = %s\n", hex(cursorText))
+	fmt.Fprintf(w, "cursor_border = %s\n", hex("cursorColor"))
+
+	for _, field := range [][2]string{{"selection_bg", "highlightColor"}, {"selection_fg", "highlightTextColor"}} {
+		if _, found := p.keys[field[1]]; found {
+			fmt.Fprintf(w, "%s = %s\n", field[0], hex(field[1]))
+		}
+	}
+
+	for i, name := range []string{"ansi", "brights"} {
+		entries := make([]string, 0, 8)
+		for n := 0; n < 8; n++ {
+			entries = append(entries, hex(fmt.Sprintf("color%d", i*8+n)))
+		}
+
+		fmt.Fprintf(w, "%s = [%s]\n", name, strings.Join(entries, ", "))
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "[metadata]")
+	fmt.Fprintf(w, "name = %q\n", sname)
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// windowsTerminalFields maps the fields of a Windows Terminal color scheme
+// to the Xresources keys they set. Windows Terminal calls magenta
+// "purple".
+var windowsTerminalFields = map[string]string{
+	"foreground":          "foreground",
+	"background":          "background",
+	"cursorColor":         "cursorColor",
+	"selectionBackground": "highlightColor",
+}
+
+func init() {
+	for i, name := range windowsTerminalColorNames() {
+		windowsTerminalFields[name] = fmt.Sprintf("color%d", i)
+	}
+}
+
+// windowsTerminalColorNames returns the 16 palette field names in order.
+func windowsTerminalColorNames() []string {
+	names := make([]string, 0, 16)
+	for _, prefix := range []string{"", "bright"} {
+		for _, name := range ansiColorNames {
+			if name == "magenta" {
+				name = "purple"
+			}
+
+			if prefix != "" {
+				name = prefix + strings.ToUpper(name[:1]) + name[1:]
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func detectWindowsTerminal(content string) bool {
+	content = strings.TrimSpace(content)
+	return (strings.HasPrefix(content, "{") || strings.HasPrefix(content, "//")) &&
+		(strings.Contains(content, `"brightWhite"`) || strings.Contains(content, `"schemes"`))
+}
+
+// windowsTerminalScheme finds the scheme to convert, which is either the
+// whole file or, for a full settings.json, the one selected by name from
+// its "schemes" array.
+func windowsTerminalScheme(lines []sourceLine, opts decodeOptions) (map[string]string, error) {
+	content := stripJSONComments([]byte(joinLines(lines)))
+
+	var settings struct {
+		Schemes []map[string]string `json:"schemes"`
+	}
+
+	if err := json.Unmarshal(content, &settings); err != nil || settings.Schemes == nil {
+		var scheme map[string]string
+		if err := json.Unmarshal(content, &scheme); err != nil {
+			return nil, fmt.Errorf("invalid Windows Terminal JSON: %s", err.Error())
+		}
+
+		return scheme, nil
+	}
+
+	names := make([]string, 0, len(settings.Schemes))
+	for _, scheme := range settings.Schemes {
+		if opts.scheme != "" && scheme["name"] == opts.scheme {
+			return scheme, nil
+		}
+
+		names = append(names, scheme["name"])
+	}
+
+	switch {
+	case opts.scheme != "":
+		return nil, fmt.Errorf("no scheme named %q in settings file, available schemes: %s", opts.scheme, strings.Join(names, ", "))
+	case len(settings.Schemes) == 1:
+		return settings.Schemes[0], nil
+	}
+
+	return nil, fmt.Errorf("settings file has %d schemes, pick one with --scheme: %s", len(settings.Schemes), strings.Join(names, ", "))
+}
+
+// parseWindowsTerminal reads a Windows Terminal color scheme, either as a
+// bare scheme object or from a full settings.json file.
+func parseWindowsTerminal(lines []sourceLine, opts decodeOptions) (map[string]resource, error) {
+	scheme, err := windowsTerminalScheme(lines, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]resource{}
+	for field, key := range windowsTerminalFields {
+		if value, found := scheme[field]; found {
+			debugf("%s: using %s: %s", lines[0].file, field, value)
+			values[key] = resource{value: value, source: fieldSource(lines, field)}
+		}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "Windows Terminal scheme")
+	return values, nil
+}
+
+// windowsTerminalName returns the name of the selected scheme.
+func windowsTerminalName(lines []sourceLine, opts decodeOptions) string {
+	scheme, err := windowsTerminalScheme(lines, opts)
+	if err != nil {
+		return ""
+	}
+
+	return scheme["name"]
+}
+
+// windowsTerminalOutput is a Windows Terminal color scheme, with the
+// fields in the order the settings UI writes them.
+type windowsTerminalOutput struct {
+	Name                string `json:"name"`
+	Background          string `json:"background"`
+	Foreground          string `json:"foreground"`
+	CursorColor         string `json:"cursorColor"`
+	SelectionBackground string `json:"selectionBackground"`
+	Black               string `json:"black"`
+	Red                 string `json:"red"`
+	Green               string `json:"green"`
+	Yellow              string `json:"yellow"`
+	Blue                string `json:"blue"`
+	Purple              string `json:"purple"`
+	Cyan                string `json:"cyan"`
+	White               string `json:"white"`
+	BrightBlack         string `json:"brightBlack"`
+	BrightRed           string `json:"brightRed"`
+	BrightGreen         string `json:"brightGreen"`
+	BrightYellow        string `json:"brightYellow"`
+	BrightBlue          string `json:"brightBlue"`
+	BrightPurple        string `json:"brightPurple"`
+	BrightCyan          string `json:"brightCyan"`
+	BrightWhite         string `json:"brightWhite"`
+}
+
+// writeWindowsTerminal writes the colors as a Windows Terminal color
+// scheme, ready to be pasted into the "schemes" array of settings.json.
+// The selection falls back to the foreground when the input has none.
+func writeWindowsTerminal(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	hex := func(key string) string {
+		return strings.ToUpper(hexColor(p.keys[key]))
+	}
+
+	scheme := windowsTerminalOutput{
+		Name:                sname,
+		Background:          hex("background"),
+		Foreground:          hex("foreground"),
+		CursorColor:         hex("cursorColor"),
+		SelectionBackground: hex("foreground"),
+	}
+
+	if _, found := p.keys["highlightColor"]; found {
+		scheme.SelectionBackground = hex("highlightColor")
+	}
+
+	slots := []*string{
+		&scheme.Black, &scheme.Red, &scheme.Green, &scheme.Yellow,
+		&scheme.Blue, &scheme.Purple, &scheme.Cyan, &scheme.White,
+		&scheme.BrightBlack, &scheme.BrightRed, &scheme.BrightGreen, &scheme.BrightYellow,
+		&scheme.BrightBlue, &scheme.BrightPurple, &scheme.BrightCyan, &scheme.BrightWhite,
+	}
+
+	for i, field := range slots {
+		*field = hex(fmt.Sprintf("color%d", i))
+	}
+
+	out, err := json.MarshalIndent(scheme, "", "    ")
+	if err != nil {
+		return fmt.Errorf("unable to encode the scheme: %s", err.Error())
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// writeOutputFile writes data to path through a temporary file in the same
+// directory, renamed into place once it's complete, so a program reading
+// the file never sees it half written. An existing file is only replaced
+// when force is set, and when backup is set, its previous contents are
+// first saved to path+".bak". New files get the permissions allowed by the
+// umask, like a shell redirection would give them.
+func writeOutputFile(path string, data []byte, force, backup bool) error {
+	return withCode(exitOutput, writeFile(path, data, force, backup))
+}
+
+func writeFile(path string, data []byte, force, backup bool) error {
+	old, err := os.ReadFile(path)
+	exists := err == nil
+
+	switch {
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("can't check the existing %q: %s", path, err.Error())
+	case exists && !force:
+		return fmt.Errorf("%q already exists, pass --force to replace it", path)
+	case exists && backup:
+		if err := writeFile(path+".bak", old, true, false); err != nil {
+			return fmt.Errorf("can't back up %q: %s", path, err.Error())
+		}
+
+		debugf("saved the previous contents of %s to %s.bak", path, path)
+	}
+
+	f, tmp, err := createTemp(path)
+	if err != nil {
+		return fmt.Errorf("can't write %q: %s", path, err.Error())
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("can't write %q through the temporary file %q: %s", path, tmp, err.Error())
+	}
+
+	return nil
+}
+
+// createTemp creates a new file next to path to write its contents to.
+// Unlike os.CreateTemp, which always uses 0600, it lets the umask pick the
+// permissions.
+func createTemp(path string) (*os.File, string, error) {
+	dir, base := filepath.Split(path)
+
+	for i := 0; ; i++ {
+		tmp := filepath.Join(dir, "."+base+".tmp"+strconv.FormatUint(uint64(rand.Uint32()), 36))
+
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, fs.ErrExist) && i < 100 {
+			continue
+		}
+
+		return f, tmp, err
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var xfceSettings = map[string]string{
+	"ColorForeground": "foreground",
+	"ColorBackground": "background",
+	"ColorCursor":     "cursorColor",
+}
+
+func detectXfce(content string) bool {
+	return strings.Contains(content, "ColorPalette=")
+}
+
+// parseXfce reads the palette of an xfce4-terminal terminalrc file, where
+// the 16 colors are stored in a single semicolon-separated ColorPalette
+// setting. Most files don't set ColorCursor, in which case the foreground
+// is used instead.
+func parseXfce(lines []sourceLine, _ decodeOptions) (map[string]resource, error) {
+	values := map[string]resource{}
+
+	for _, e := range parseINI(lines) {
+		if key, found := xfceSettings[e.key]; found {
+			debugf("%s: using %s: %s", e.source, e.key, e.value)
+			values[key] = e.resource()
+			continue
+		}
+
+		if e.key != "ColorPalette" {
+			continue
+		}
+
+		palette := strings.Split(strings.TrimRight(e.value, ";"), ";")
+		if len(palette) != 16 {
+			return nil, fmt.Errorf("%s: ColorPalette must have 16 colors, found %d", e.source, len(palette))
+		}
+
+		for i, value := range palette {
+			debugf("%s: using ColorPalette entry %d: %s", e.source, i, value)
+			values[fmt.Sprintf("color%d", i)] = resource{value: strings.TrimSpace(value), source: e.source}
+		}
+	}
+
+	fallbackKey(values, "cursorColor", "foreground", "xfce4-terminal configuration")
+	return values, nil
+}
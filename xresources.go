@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	reParseItems = regexp.MustCompile(`^[ \t]*(URxvt|Rxvt|XTerm|xterm)?([.*]+)((?i:color[0-9]+|colorBD|colorIT|colorUL|foreground|background|cursorColor2|cursorColor|highlightColor|highlightTextColor))[ \t]*:(.*)$`)
+	reDefines    = regexp.MustCompile(`^[ \t]*#define[ \t]+([A-Za-z_][A-Za-z0-9_]*)[ \t]+(.*?)[ \t]*$`)
+	reIncludes   = regexp.MustCompile(`^[ \t]*#include[ \t]+["<]([^">]+)[">]`)
+	reMacroName  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	rePalette    = regexp.MustCompile(`^(?i:color)([0-9]+)$`)
+)
+
+const (
+	maxMacroDepth   = 16
+	maxIncludeDepth = 16
+)
+
+// detectXresources reports whether any line looks like a color resource
+// or a preprocessor directive that could pull one in. It's tried last,
+// since "color0:" style lines show up in YAML themes too.
+func detectXresources(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if reParseItems.MatchString(line) || reIncludes.MatchString(line) || reDefines.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expandIncludes replaces "#include" directives with the lines of the
+// included file, resolved relative to the directory of the file that
+// includes it, the same way the C preprocessor used by xrdb does.
+func expandIncludes(lines []sourceLine) ([]sourceLine, error) {
+	return expandIncludesDepth(lines, 0)
+}
+
+func expandIncludesDepth(lines []sourceLine, depth int) ([]sourceLine, error) {
+	expanded := make([]sourceLine, 0, len(lines))
+
+	for _, line := range lines {
+		m := reIncludes.FindStringSubmatch(line.text)
+		if m == nil {
+			expanded = append(expanded, line)
+			continue
+		}
+
+		if depth == maxIncludeDepth {
+			return nil, fmt.Errorf("%s: includes are nested too deeply, there might be a cycle", line)
+		}
+
+		included, err := resolveInclude(line.file, m[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: unable to resolve include %q: %s", line, m[1], err.Error())
+		}
+
+		debugf("%s: including file %q", line, included)
+
+		inner, err := readLines(included)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't include file: %s", line, err.Error())
+		}
+
+		inner, err = expandIncludesDepth(inner, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, inner...)
+	}
+
+	return expanded, nil
+}
+
+// resolveInclude returns the path of an included file, expanding "~" to
+// the user's home directory and resolving relative paths against the
+// directory of the including file, or against its URL if it was
+// downloaded.
+func resolveInclude(from, name string) (string, error) {
+	if isURL(from) {
+		return resolveURL(from, name)
+	}
+
+	if name == "~" || strings.HasPrefix(name, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(home, name[1:]), nil
+	}
+
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+
+	return filepath.Join(filepath.Dir(from), name), nil
+}
+
+// parseXresources finds all the color resources in the given lines.
+// When the same key is defined more than once, the most specific entry
+// wins, mirroring xrdb: a class-qualified entry such as "URxvt.color0"
+// beats a wildcard one such as "*.color0", and a tight binding (".") beats
+// a loose one ("*"). Entries with the same specificity are resolved in
+// favor of the last one defined. Values referencing a "#define" macro are
+// replaced with the macro contents, and "#include" directives are followed
+// unless disabled.
+func parseXresources(lines []sourceLine, opts decodeOptions) (map[string]resource, error) {
+	if opts.includes {
+		var err error
+		if lines, err = expandIncludes(lines); err != nil {
+			return nil, err
+		}
+	}
+
+	macros := map[string]string{}
+	for _, line := range lines {
+		if m := reDefines.FindStringSubmatch(line.text); m != nil {
+			macros[m[1]] = m[2]
+		}
+	}
+
+	values := map[string]resource{}
+
+	for _, line := range lines {
+		if isComment(line.text) {
+			if strings.TrimSpace(line.text) != "" {
+				tracef("%s: skipping, it's a comment", line)
+			}
+
+			continue
+		}
+
+		if idx := reParseItems.FindStringSubmatchIndex(line.text); idx == nil {
+			if strings.TrimSpace(line.text) != "" && !reDefines.MatchString(line.text) && !reIncludes.MatchString(line.text) {
+				tracef("%s: skipping, it isn't a color resource: %s", line, strings.TrimSpace(line.text))
+			}
+		} else {
+			class, binding := submatch(line.text, idx, 1), submatch(line.text, idx, 2)
+			key, value := canonicalKey(submatch(line.text, idx, 3)), resourceValue(submatch(line.text, idx, 4))
+			if value == "" {
+				debugf("%s: skipping %s%s%s, it has no value", line, class, binding, key)
+				continue
+			}
+
+			if m := rePalette.FindStringSubmatch(key); m != nil {
+				n, err := strconv.Atoi(m[1])
+				if err != nil || n > 15 {
+					debugf("%s: skipping %s%s%s, KiTTY only has 16 palette colors", line, class, binding, key)
+					continue
+				}
+
+				key = fmt.Sprintf("color%d", n)
+			}
+
+			raw := value
+			value, err := expandMacro(macros, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: unable to expand value for key %q: %s", line, key, err.Error())
+			}
+
+			current := resource{
+				value:       value,
+				source:      line,
+				specificity: resourceSpecificity(class, binding),
+			}
+
+			if prev, found := values[key]; found && prev.specificity > current.specificity {
+				debugf("%s: ignoring %s%s%s in favor of the more specific entry on %s", line, class, binding, key, prev.source)
+				continue
+			}
+
+			if value != raw {
+				debugf("%s: using %s%s%s: %s, expanded to %s", line, class, binding, key, raw, value)
+			} else {
+				debugf("%s: using %s%s%s: %s", line, class, binding, key, value)
+			}
+
+			values[key] = current
+		}
+	}
+
+	return values, nil
+}
+
+// expandMacro replaces value with the contents of the macro it names, if
+// any, following chains of macros referencing other macros. When the file
+// defines macros, values that look like a macro name but are neither a
+// defined macro nor a known X11 color name are reported as undefined.
+func expandMacro(macros map[string]string, value string) (string, error) {
+	if len(macros) == 0 {
+		return value, nil
+	}
+
+	for depth := 0; reMacroName.MatchString(value); depth++ {
+		expanded, found := macros[value]
+		if !found {
+			if _, err := namedToRGB(value); err != nil {
+				return "", fmt.Errorf("undefined macro %q", value)
+			}
+
+			break
+		}
+
+		if depth == maxMacroDepth {
+			return "", fmt.Errorf("macro %q is nested too deeply, there might be a cycle", value)
+		}
+
+		value = expanded
+	}
+
+	return value, nil
+}
+
+// resourceValue extracts the color from the raw text after the colon,
+// dropping trailing "!" or "//" comments. Hex and "rgb:" values end at the
+// first whitespace, so any text after them is ignored, while color names
+// such as "dark slate gray" are kept whole.
+func resourceValue(raw string) string {
+	for _, marker := range []string{"!", "//"} {
+		if pos := strings.Index(raw, marker); pos >= 0 {
+			raw = raw[:pos]
+		}
+	}
+
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "#") || strings.HasPrefix(raw, "rgb:") {
+		if fields := strings.Fields(raw); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	return raw
+}
+
+// isComment reports whether the line is commented out, either with the
+// Xresources "!" or with the "//" some themes use.
+func isComment(line string) bool {
+	line = strings.TrimLeft(line, " \t")
+	return strings.HasPrefix(line, "!") || strings.HasPrefix(line, "//")
+}
+
+// isKnownKey reports whether the key is one of the resources this tool
+// reads, either to convert it or to report it as ignored.
+func isKnownKey(key string) bool {
+	_, required := nameReplacements[key]
+	_, optional := optionalReplacements[key]
+	return required || optional || contains(ignoredKeys, key) || contains(unsupportedKeys, key)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canonicalKey returns the spelling of a resource name used internally,
+// since urxvt accepts "cursorcolor" or "CURSORCOLOR" as "cursorColor".
+func canonicalKey(name string) string {
+	for _, keys := range []map[string][]int{nameReplacements, optionalReplacements} {
+		for key := range keys {
+			if strings.EqualFold(key, name) {
+				return key
+			}
+		}
+	}
+
+	for _, keys := range [][]string{ignoredKeys, unsupportedKeys} {
+		for _, key := range keys {
+			if strings.EqualFold(key, name) {
+				return key
+			}
+		}
+	}
+
+	return name
+}
+
+// resourceSpecificity ranks resource paths: wildcard entries rank the
+// lowest, followed by the XTerm class and then the urxvt classes, since
+// "xrdb -query" output often carries XTerm and URxvt entries side by side.
+// Within a class, a tight binding ranks higher than a loose one.
+func resourceSpecificity(class, binding string) int {
+	rank := 0
+
+	switch class {
+	case "":
+		return 0
+	case "URxvt", "Rxvt":
+		rank = 3
+	default:
+		rank = 1
+	}
+
+	if binding == "." {
+		rank++
+	}
+
+	return rank
+}
+
+func submatch(s string, idx []int, n int) string {
+	if idx[2*n] < 0 {
+		return ""
+	}
+
+	return s[idx[2*n]:idx[2*n+1]]
+}
+
+// writeXresources writes the colors as wildcard Xresources entries, in a
+// fixed order, so feeding the output back in gives the same output. The
+// optional keys are only written when the input sets them.
+func writeXresources(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	keys := []string{"foreground", "background", "cursorColor", "cursorColor2", "colorBD", "colorIT", "colorUL", "highlightColor", "highlightTextColor"}
+
+	fmt.Fprintf(w, "! %s\n", sname)
+
+	for _, key := range append(keys, paletteKeys()...) {
+		if c, found := p.keys[key]; found {
+			fmt.Fprintf(w, "*.%s: %s\n", key, hexColor(c))
+		}
+	}
+
+	return nil
+}
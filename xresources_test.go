@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"cursorColor", "cursorColor"},
+		{"cursorcolor", "cursorColor"},
+		{"Cursorcolor", "cursorColor"},
+		{"CURSORCOLOR", "cursorColor"},
+		{"cursorcolor2", "cursorColor2"},
+		{"Foreground", "foreground"},
+		{"COLOR12", "color12"},
+		{"colorbd", "colorBD"},
+		{"HIGHLIGHTCOLOR", "highlightColor"},
+		{"highlighttextcolor", "highlightTextColor"},
+	}
+
+	for _, tt := range tests {
+		if got := canonicalKey(tt.name); got != tt.want {
+			t.Errorf("canonicalKey(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMixedCaseKeys(t *testing.T) {
+	values := readTestValues(t, "mixed-case.Xresources")
+
+	want := map[string]string{
+		"foreground":   "#c5c8c6",
+		"background":   "#1d1f21",
+		"cursorColor":  "#aeafad",
+		"cursorColor2": "#1d1f21",
+		"colorBD":      "#ffffff",
+		"color0":       "#282a2e",
+		"color8":       "#373b41",
+		"color11":      "#f0c674",
+	}
+
+	for key, value := range want {
+		if got := values[key].value; got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if _, err := convert(values); err != nil {
+		t.Errorf("convert() = %s, want every key found", err)
+	}
+}
+
+func TestWhitespaceAroundValues(t *testing.T) {
+	for _, name := range []string{"tab-aligned.Xresources", "no-space.Xresources"} {
+		values := readTestValues(t, name)
+
+		for key, value := range map[string]string{
+			"foreground":  "#c5c8c6",
+			"cursorColor": "#c5c8c6",
+			"color0":      "#1d1f21",
+			"color8":      "#969896",
+			"color7":      "#c5c8c6",
+			"color15":     "#ffffff",
+		} {
+			if got := values[key].value; got != value {
+				t.Errorf("%s: %s = %q, want %q", name, key, got, value)
+			}
+		}
+
+		if _, err := convert(values); err != nil {
+			t.Errorf("%s: convert() = %s", name, err)
+		}
+	}
+}
+
+func TestCommentedDuplicates(t *testing.T) {
+	values := readTestValues(t, "commented-duplicates.Xresources")
+
+	for key, value := range map[string]string{
+		"foreground":  "#839496",
+		"background":  "#002b36",
+		"cursorColor": "#93a1a1",
+		"color1":      "#dc322f",
+		"color2":      "#859900",
+		"color4":      "#268bd2",
+	} {
+		if got := values[key]; got.value != value {
+			t.Errorf("%s = %q from %s, want the live value %q", key, got.value, got.source, value)
+		}
+	}
+}
+
+func TestIsComment(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"! *.color4: #0000ff", true},
+		{"   ! indented", true},
+		{"\t// *.color1: #ff0000", true},
+		{"//no space", true},
+		{"*.color4: #0000ff", false},
+		{"*.color4: #0000ff ! blue", false},
+		{"#define blue #0000ff", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isComment(tt.line); got != tt.want {
+			t.Errorf("isComment(%q) = %t, want %t", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestXrdbQuery(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "xrdb-query.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	// the dump is piped to stdin, as in "xrdb -query | urxvt-kitty - laptop"
+	lines, err := scanLines("-", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format, err := findInputFormat("", lines)
+	if err != nil || format.name != "xresources" {
+		t.Fatalf("findInputFormat() = %q, %v, want xresources", format.name, err)
+	}
+
+	values, err := parseXresources(lines, decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range map[string]string{
+		"foreground":  "#e0e0e0",
+		"background":  "#202020",
+		"cursorColor": "#c5c8c6",
+		"color0":      "#282a2e",
+		"color1":      "#aa0000",
+		"color4":      "#5f819d",
+		"color15":     "#ffffff",
+	} {
+		if got := values[key].value; got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if _, err := convert(values); err != nil {
+		t.Errorf("convert() = %s", err)
+	}
+}
+
+func TestResourceSpecificity(t *testing.T) {
+	order := [][2]string{{"", "*"}, {"", "."}, {"XTerm", "*"}, {"XTerm", "."}, {"URxvt", "*"}, {"URxvt", "."}}
+	for i := 1; i < len(order); i++ {
+		prev, cur := order[i-1], order[i]
+		if resourceSpecificity(prev[0], prev[1]) > resourceSpecificity(cur[0], cur[1]) {
+			t.Errorf("%s%scolor0 ranks above %s%scolor0", prev[0], prev[1], cur[0], cur[1])
+		}
+	}
+
+	if resourceSpecificity("URxvt", "*") <= resourceSpecificity("", "*") {
+		t.Error("URxvt*color0 doesn't rank above *color0")
+	}
+}
+
+func TestExtendedPalette(t *testing.T) {
+	values := readTestValues(t, "palette256.Xresources")
+
+	for i := 16; i < 256; i++ {
+		if res, found := values[fmt.Sprintf("color%d", i)]; found {
+			t.Errorf("color%d from %s was kept, KiTTY only has 16 palette colors", i, res.source)
+		}
+	}
+
+	if len(values) != 19 {
+		t.Errorf("got %d keys, want the 19 of the base palette", len(values))
+	}
+
+	for key, value := range map[string]string{
+		"color1":  "#cc6666",
+		"color10": "#a3c06a",
+		"color15": "#ffffff",
+	} {
+		if got := values[key].value; got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	p, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.slots) != 22 {
+		t.Errorf("got %d session colors, want 22", len(p.slots))
+	}
+
+	f, err := findOutputFormat("kitty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.encode(&b, "palette256", p); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "palette256.reg", b.Bytes())
+}
+
+func TestInlineComments(t *testing.T) {
+	values := readTestValues(t, "inline-comments.Xresources")
+
+	for key, value := range map[string]string{
+		"foreground": "#ebdbb2",
+		"background": "#282828",
+		"color1":     "#cc241d",
+		"color9":     "#fb4934",
+		"color2":     "#98971a",
+		"color4":     "#458588",
+	} {
+		if got := values[key].value; got != value {
+			t.Errorf("%s = %q, want the first value %q", key, got, value)
+		}
+	}
+
+	if _, err := convert(values); err != nil {
+		t.Errorf("convert() = %s", err)
+	}
+}
+
+func TestResourceValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{" #cc6666", "#cc6666"},
+		{" #cc6666   ! red", "#cc6666"},
+		{" #cc6666 // red", "#cc6666"},
+		{" #cc6666 ! not #ff0000", "#cc6666"},
+		{"\t#cc6666\t#ff0000", "#cc6666"},
+		{" rgb:cc/66/66 ! red", "rgb:cc/66/66"},
+		{" dark slate gray", "dark slate gray"},
+		{" dark slate gray ! a name", "dark slate gray"},
+		{" ! nothing", ""},
+	}
+
+	for _, tt := range tests {
+		if got := resourceValue(tt.raw); got != tt.want {
+			t.Errorf("resourceValue(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestWriteXresourcesRoundTrip(t *testing.T) {
+	p := readTestPalette(t, "mixed-case.Xresources")
+
+	var first bytes.Buffer
+	if err := writeXresources(&first, "Tomorrow Night", p, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "mixed-case.normalized.Xresources", first.Bytes())
+
+	// the normalized output converts back to the same file
+	lines, err := scanLines("normalized.Xresources", bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := parseXresources(lines, decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := convert(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var second bytes.Buffer
+	if err := writeXresources(&second, "Tomorrow Night", again, encodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("the second pass changed the output\nfirst:\n%s\nsecond:\n%s", first.Bytes(), second.Bytes())
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// writeXshell writes the colors as an Xshell .xcs color scheme, with the
+// scheme in a section named after the session and the [Names] section
+// Xshell lists the schemes of the file from. Colors are hex "rrggbb"
+// without the "#", and lines end in CRLF.
+func writeXshell(w io.Writer, sname string, p palette, _ encodeOptions) error {
+	hex := func(c color.RGBA) string {
+		return strings.TrimPrefix(hexColor(c), "#")
+	}
+
+	bold := p.keys["foreground"]
+	if c, found := p.keys["colorBD"]; found {
+		bold = c
+	}
+
+	lines := []string{
+		fmt.Sprintf("[%s]", sname),
+		"text=" + hex(p.keys["foreground"]),
+		"text(bold)=" + hex(bold),
+		"background=" + hex(p.keys["background"]),
+		"cursor=" + hex(p.keys["cursorColor"]),
+	}
+
+	for i, name := range ansiColorNames {
+		lines = append(lines,
+			fmt.Sprintf("%s=%s", name, hex(p.keys[fmt.Sprintf("color%d", i)])),
+			fmt.Sprintf("%s(bold)=%s", name, hex(p.keys[fmt.Sprintf("color%d", i+8)])),
+		)
+	}
+
+	lines = append(lines, "[Names]", "name0="+sname, "count=1")
+
+	_, err := io.WriteString(w, strings.Join(lines, "\r\n")+"\r\n")
+	return err
+}